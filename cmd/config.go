@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/NoaTamburrini/portman/internal/config"
+	"github.com/NoaTamburrini/portman/internal/process"
+	"github.com/NoaTamburrini/portman/internal/scanner"
+	"github.com/NoaTamburrini/portman/internal/services"
+)
+
+// cfg is the loaded user configuration
+var cfg = config.Default()
+
+// loadConfig loads the user config and applies it to the scanner and
+// process packages. Errors are fatal unless tolerant is set.
+func loadConfig(tolerant bool) {
+	loaded, err := config.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		if !tolerant {
+			os.Exit(1)
+		}
+	}
+	cfg = loaded
+
+	if err := scanner.SetBackend(cfg.Scanner.Backend); err != nil {
+		fmt.Fprintf(os.Stderr, "Error in config: %v\n", err)
+		if !tolerant {
+			os.Exit(1)
+		}
+	}
+
+	scanner.SetAllNamespaces(cfg.Scanner.AllNamespaces)
+	process.SetPolicy(protectionPolicy(cfg.Protected))
+
+	etcServices := ""
+	if cfg.WellKnown.EtcServices {
+		etcServices = services.EtcServicesPath
+	}
+	services.Configure(cfg.WellKnown.Ports, etcServices)
+}
+
+// protectionPolicy builds the process protection policy from config
+func protectionPolicy(p config.Protected) process.Policy {
+	var policy process.Policy
+	if p.IncludeDefaults {
+		policy = process.DefaultPolicy
+	}
+
+	policy.Names = append(append([]string{}, policy.Names...), p.Names...)
+	policy.Paths = append(append([]string{}, policy.Paths...), p.Paths...)
+	policy.Users = append(append([]string{}, policy.Users...), p.Users...)
+	policy.Ports = append(append([]int{}, policy.Ports...), p.Ports...)
+	return policy
+}
+
+// killOptions returns the configured kill options
+func killOptions() process.KillOptions {
+	sig, err := process.ParseSignal(cfg.Kill.Signal)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error in config: %v\n", err)
+		os.Exit(1)
+	}
+
+	return process.KillOptions{
+		Signal:  sig,
+		Timeout: cfg.Kill.Timeout.Duration,
+	}
+}
+
+func executeConfig(args []string) {
+	if len(args) != 1 {
+		fmt.Println("Usage: portman config show|path|edit")
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "show":
+		data, err := cfg.Marshal()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("# %s\n%s", config.Path(), data)
+
+	case "path":
+		fmt.Println(config.Path())
+
+	case "edit":
+		if err := config.WriteDefault(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error creating config: %v\n", err)
+			os.Exit(1)
+		}
+
+		editor := os.Getenv("VISUAL")
+		if editor == "" {
+			editor = os.Getenv("EDITOR")
+		}
+		if editor == "" {
+			editor = "vi"
+		}
+
+		// EDITOR may carry arguments, e.g. "code --wait"
+		parts := strings.Fields(editor)
+		cmd := exec.Command(parts[0], append(parts[1:], config.Path())...)
+		cmd.Stdin = os.Stdin
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error running %s: %v\n", editor, err)
+			os.Exit(1)
+		}
+
+		if _, err := config.Load(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			os.Exit(1)
+		}
+
+	default:
+		fmt.Printf("Unknown config command: %s\n", args[0])
+		fmt.Println("Usage: portman config show|path|edit")
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/NoaTamburrini/portman/internal/scanner"
+)
+
+func executeConnections(args []string) {
+	fs := newFlagSet("connections", "portman connections [--json] [--all-netns] <port|service|alias>")
+	asJSON := fs.Bool("json", false, "print connections as JSON")
+	addScanFlags(fs)
+	positional := parseArgs(fs, args)
+	if len(positional) != 1 {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	portNum, err := resolvePort(positional[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+
+	ports, err := scanner.ScanPorts()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error scanning ports: %v\n", err)
+		os.Exit(1)
+	}
+
+	// UDP is connectionless
+	listeners, _ := scanner.FilterProtocol(scanner.FindAllByPort(ports, portNum), "tcp")
+	if len(listeners) == 0 {
+		fmt.Printf("Nothing listening on TCP port %d\n", portNum)
+		os.Exit(1)
+	}
+
+	if *asJSON {
+		conns := []scanner.Connection{}
+		for _, p := range listeners {
+			conns = append(conns, p.Connections...)
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(conns)
+		return
+	}
+
+	for _, p := range listeners {
+		fmt.Printf("Port %d: PID %d (%s), %d connection(s)\n", p.Number, p.PID, p.ProcessName, len(p.Connections))
+		if len(p.Connections) == 0 {
+			continue
+		}
+
+		header := fmt.Sprintf("  %-40s %-12s %-28s", "REMOTE", "STATE", "CLIENT")
+		if scanner.QueuesKnown() {
+			header += fmt.Sprintf(" %8s %8s", "SEND-Q", "RECV-Q")
+		}
+		fmt.Println(header)
+		for _, c := range p.Connections {
+			client := "-"
+			if c.ClientPID != 0 {
+				client = fmt.Sprintf("%s (PID %d)", c.ClientName, c.ClientPID)
+			}
+			line := fmt.Sprintf("  %-40s %-12s %-28s", c.Remote, c.State, client)
+			if scanner.QueuesKnown() {
+				line += fmt.Sprintf(" %8d %8d", c.SendQueue, c.RecvQueue)
+			}
+			fmt.Println(line)
+		}
+	}
+}
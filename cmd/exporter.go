@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/NoaTamburrini/portman/internal/metrics"
+	"github.com/NoaTamburrini/portman/internal/server"
+)
+
+func executeExporter(args []string) {
+	fs := newFlagSet("exporter", "portman exporter [--listen 127.0.0.1:9464|unix:/path] [--all-netns]")
+	listen := fs.String("listen", cfg.Exporter.Listen, `address to listen on, host:port or "unix:/path/to/socket"`)
+	addScanFlags(fs)
+	if positional := parseArgs(fs, args); len(positional) > 0 {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	listener, err := listenOn(*listen)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	exporter := metrics.New()
+	exporter.AuditLog = metricsAuditLog()
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", exporter)
+	srv := &http.Server{Handler: server.CheckHost(mux, listenHosts(*listen)...), ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdown)
+	}()
+
+	fmt.Printf("✓ Serving metrics on %s/metrics\n", describeListener(listener))
+	if err := srv.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// metricsAuditLog returns the audit log to count kills from, or "" when
+// kills aren't being logged
+func metricsAuditLog() string {
+	if !cfg.Audit.Enabled {
+		return ""
+	}
+	return auditPath()
+}
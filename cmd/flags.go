@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/NoaTamburrini/portman/internal/scanner"
+)
+
+// newFlagSet creates a flag set for a subcommand with a usage line
+func newFlagSet(name, usage string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s\n\nOptions:\n", usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parseArgs parses flags that may appear before or after positional
+// arguments and returns the positional arguments. It exits on bad flags.
+func parseArgs(fs *flag.FlagSet, args []string) []string {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				os.Exit(0)
+			}
+			os.Exit(1)
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			break
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+	return positional
+}
+
+// addScanFlags registers flags that change how ports are scanned
+func addScanFlags(fs *flag.FlagSet) {
+	fs.BoolFunc("all-netns", "also scan other network namespaces (Linux, needs root for other users' processes)", func(string) error {
+		scanner.SetAllNamespaces(true)
+		return nil
+	})
+}
+
+// addProtoFlag registers the --proto filter
+func addProtoFlag(fs *flag.FlagSet) *string {
+	return fs.String("proto", "all", "only include ports of this protocol: "+strings.Join(scanner.Protocols, ", "))
+}
+
+// filterProtocol applies the --proto filter, exiting on an unknown protocol
+func filterProtocol(ports []scanner.Port, protocol string) []scanner.Port {
+	filtered, err := scanner.FilterProtocol(ports, protocol)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+	return filtered
+}
@@ -7,20 +7,51 @@ func printHelp() {
 
 Usage:
   portman              Launch interactive TUI
-  portman kill <port>  Kill process on specific port
+  portman --force-protected  Launch TUI allowing protected processes to be killed
+  portman kill <port>  Kill process on specific port (or a service name or alias)
+  portman list         List listening ports (--json, --wide, --timing, --proto udp, --older-than 2h, --project NAME)
+  portman status       Show health of services in .portman (--json, --probe)
+  portman connections <port>  Show the clients connected to a port (--json)
+  portman pause <port> Suspend the process on a port (SIGSTOP) until resumed
+  portman resume <port> Continue a paused process (SIGCONT)
+  portman kills        Show the audit log of kills (--port, --user, --process, --since 24h, --json)
+  portman serve        Serve an HTTP/JSON API (--listen 127.0.0.1:7070 or unix:/path, --token)
+  portman exporter     Serve Prometheus metrics on /metrics (--listen 127.0.0.1:9464)
+  portman config show|path|edit  Show, locate, or edit the config file
   portman version      Show version information
   portman help         Show this help message
 
+Kill options:
+  --dry-run           Show what would be killed without sending signals
+  --force-protected   Allow killing protected processes (sshd, systemd, PID 1, ...)
+  --signal SIG        Signal to send first (default from config, TERM)
+  --timeout 2s        Wait before escalating to SIGKILL
+  --drain             After the first signal, wait for clients to disconnect first
+  --drain-timeout 30s How long --drain waits (default from config)
+  --older-than 2h     Only processes running at least this long (port optional; also for list)
+  --proto tcp|udp|all Only ports of one protocol (also for list and TUI)
+  --kill-supervisor   Also kill the supervisor if the process respawns
+  --all-netns         Also look in other network namespaces (Linux; also for list and TUI)
+
 Keybindings (TUI):
   ↑/↓ or j/k          Navigate
   Enter               Kill selected process
   r                   Refresh port list
   /                   Filter ports
+  i                   Show details of selected port
+  c                   Show clients connected to selected port
+  u                   Show memory, CPU, thread and fd columns
+  s                   Sort by port or uptime
+  K                   Kill supervisor of a respawned process
+  R                   Relaunch the last killed process
+  z / Z               Pause / resume selected process
+  Esc                 Cancel a running scan, or stop waiting for a kill
   q or Ctrl+C         Quit
 
 Examples:
   portman              # Launch interactive mode
   portman kill 3000    # Kill process on port 3000
+  portman kill 3000 --dry-run  # Preview the kill
   portman version      # Show version
 `
 	fmt.Println(help)
@@ -1,59 +1,137 @@
 package cmd
 
 import (
+	"bufio"
+	"context"
 	"fmt"
 	"os"
-	"strconv"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
 
+	"github.com/NoaTamburrini/portman/internal/container"
 	"github.com/NoaTamburrini/portman/internal/process"
 	"github.com/NoaTamburrini/portman/internal/scanner"
+	"github.com/NoaTamburrini/portman/internal/systemd"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 )
 
-func executeKill() {
-	if len(os.Args) < 3 {
-		fmt.Println("Usage: portman kill <port>")
+func executeKill(args []string) {
+	fs := newFlagSet("kill", "portman kill [--dry-run] [--force-protected] [--signal SIG] [--timeout 2s] [--drain] [--drain-timeout 30s] [--older-than 2h] [--proto tcp|udp|all] [--all-netns] [--kill-supervisor] <port|service|alias>")
+	dryRun := fs.Bool("dry-run", false, "show which processes would be signalled without killing them")
+	forceProtected := fs.Bool("force-protected", false, "allow killing processes protected by policy")
+	olderThan := fs.Duration("older-than", 0, "only kill processes running for at least this long; the port is optional then")
+	proto := addProtoFlag(fs)
+	addScanFlags(fs)
+	killSupervisor := fs.Bool("kill-supervisor", false, "if a killed process is restarted, kill the process manager that restarted it")
+	opts := killOptions()
+	signal := fs.String("signal", process.SignalName(opts.Signal), "signal to send first")
+	fs.DurationVar(&opts.Timeout, "timeout", opts.Timeout, "how long to wait before escalating to SIGKILL")
+	drain := fs.Bool("drain", false, "after the first signal, wait for clients to disconnect before the timeout starts")
+	drainTimeout := fs.Duration("drain-timeout", cfg.Kill.DrainTimeout.Duration, "how long --drain waits for clients to disconnect")
+	positional := parseArgs(fs, args)
+
+	// Without a port, --older-than picks from every port
+	if len(positional) > 1 || (len(positional) == 0 && *olderThan <= 0) {
+		fs.Usage()
 		os.Exit(1)
 	}
 
-	portNum, err := strconv.Atoi(os.Args[2])
+	var portNum int
+	var err error
+	if len(positional) == 1 {
+		portNum, err = resolvePort(positional[0])
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	opts.Signal, err = process.ParseSignal(*signal)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Invalid port number: %s\n", os.Args[2])
+		fmt.Fprintf(os.Stderr, "%v\n", err)
 		os.Exit(1)
 	}
-
-	if portNum < 1 || portNum > 65535 {
-		fmt.Fprintf(os.Stderr, "Port number must be between 1 and 65535\n")
+	if *drain && opts.Signal == syscall.SIGKILL {
+		fmt.Fprintln(os.Stderr, "--drain needs a signal the process can handle, not SIGKILL")
 		os.Exit(1)
 	}
+	opts.Port = portNum
+	opts.ForceProtected = *forceProtected
+	auditKills("cli")
+	ctx := context.Background()
 
 	// Scan to find all processes on the port
-	ports, err := scanner.ScanPorts()
+	ports, err := scanner.ScanPortsContext(ctx)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error scanning ports: %v\n", err)
 		os.Exit(1)
 	}
 
-	matches := scanner.FindAllByPort(ports, portNum)
+	matches := killTargets(ports, *proto, portNum)
+	if portNum != 0 {
+		if len(matches) == 0 {
+			fmt.Printf("No process found on port %d\n", portNum)
+			os.Exit(1)
+		}
+	}
+
+	if *olderThan > 0 {
+		matches = scanner.OlderThan(matches, *olderThan)
+		if len(matches) == 0 {
+			if portNum != 0 {
+				fmt.Printf("No process running for over %s on port %d\n", *olderThan, portNum)
+			} else {
+				fmt.Printf("No process running for over %s\n", *olderThan)
+			}
+			os.Exit(1)
+		}
+		sort.Slice(matches, func(i, j int) bool {
+			return matches[i].Number < matches[j].Number
+		})
+	}
+
+	// Published container ports are held by a proxy process; killing it
+	// breaks the container runtime instead of stopping the container
+	containers, matches := splitContainers(matches)
+
+	if *dryRun {
+		if *drain {
+			opts.Drain = &process.Drain{Timeout: *drainTimeout}
+		}
+		printDryRun(containers, matches, opts)
+		return
+	}
+
+	for _, c := range containers {
+		offerContainerStop(ctx, c, opts)
+	}
+
+	// Signalling a supervised process usually just gets it restarted
+	matches = offerUnitStops(ctx, matches, opts)
 	if len(matches) == 0 {
-		fmt.Printf("No process found on port %d\n", portNum)
-		os.Exit(1)
+		return
 	}
 
-	// If only one process, kill it directly
-	if len(matches) == 1 {
+	// If only one process on the port, kill it directly
+	if len(matches) == 1 && portNum != 0 {
 		port := matches[0]
 		fmt.Printf("Killing process on port %d (PID: %d, Process: %s)...\n",
 			port.Number, port.PID, port.ProcessName)
 
-		result := process.KillProcess(port.PID)
+		if *drain {
+			opts.Drain = drainPort(port, *drainTimeout)
+		}
+		result := process.KillProcessContext(ctx, port.PID, opts)
 		if result.Success {
 			fmt.Printf("✓ %s\n", result.Message)
 		} else {
 			fmt.Fprintf(os.Stderr, "✗ %s\n", result.Message)
 			os.Exit(1)
 		}
+		watchRespawns(ctx, []scanner.Port{port}, opts, *killSupervisor)
 		return
 	}
 
@@ -66,27 +144,276 @@ func executeKill() {
 	}
 
 	// Kill all selected
-	if len(selected) == len(matches) {
+	if len(selected) == len(matches) && portNum != 0 {
 		fmt.Printf("Killing all %d processes on port %d...\n", len(selected), portNum)
 	}
 
+	var killed []scanner.Port
 	for _, p := range selected {
 		fmt.Printf("Killing PID %d (%s)...\n", p.PID, p.ProcessName)
-		result := process.KillProcess(p.PID)
+		opts.Port = p.Number
+		if *drain {
+			opts.Drain = drainPort(p, *drainTimeout)
+		}
+		result := process.KillProcessContext(ctx, p.PID, opts)
 		if result.Success {
 			fmt.Printf("✓ %s\n", result.Message)
+			killed = append(killed, p)
 		} else {
 			fmt.Fprintf(os.Stderr, "✗ %s\n", result.Message)
 		}
 	}
+	watchRespawns(ctx, killed, opts, *killSupervisor)
+}
+
+// killTargets picks the ports of one protocol, on one port unless portNum
+// is 0
+func killTargets(ports []scanner.Port, proto string, portNum int) []scanner.Port {
+	matches := filterProtocol(ports, proto)
+	if portNum != 0 {
+		matches = scanner.FindAllByPort(matches, portNum)
+	}
+	return matches
+}
+
+// drainPort waits for the clients of a port to disconnect, printing how
+// many are left at each check
+func drainPort(p scanner.Port, timeout time.Duration) *process.Drain {
+	start := time.Now()
+	return &process.Drain{
+		Timeout: timeout,
+		Connections: func(ctx context.Context) (int, error) {
+			n, err := scanner.CountEstablished(ctx, p)
+			switch {
+			case err != nil:
+				fmt.Fprintf(os.Stderr, "⚠ Can't count connections, not draining: %v\n", err)
+			case n == 0:
+				fmt.Printf("  No clients connected to port %d\n", p.Number)
+			default:
+				fmt.Printf("  [%s] %d client(s) still connected to port %d\n",
+					time.Since(start).Round(time.Second), n, p.Number)
+			}
+			return n, err
+		},
+	}
+}
+
+// watchRespawns rescans for a short while after a kill to catch processes
+// that a supervisor restarts, optionally killing the supervisor instead
+func watchRespawns(ctx context.Context, killed []scanner.Port, opts process.KillOptions, killSupervisor bool) {
+	window := cfg.Kill.RespawnWindow.Duration
+	if len(killed) == 0 || window <= 0 {
+		return
+	}
+
+	for deadline := time.Now().Add(window); time.Now().Before(deadline); {
+		time.Sleep(respawnPollInterval)
+
+		ports, err := scanner.ScanPortsContext(ctx)
+		if err != nil {
+			return
+		}
+
+		var pending []scanner.Port
+		for _, k := range killed {
+			r := scanner.DetectRespawn(k, ports)
+			if r == nil {
+				pending = append(pending, k)
+				continue
+			}
+			reportRespawn(ctx, r, opts, killSupervisor)
+		}
+		if killed = pending; len(killed) == 0 {
+			return
+		}
+	}
+}
+
+// respawnPollInterval is how often watchRespawns rescans
+const respawnPollInterval = 500 * time.Millisecond
+
+// reportRespawn warns about a restarted process and kills its supervisor
+// when asked to
+func reportRespawn(ctx context.Context, r *scanner.Respawn, opts process.KillOptions, killSupervisor bool) {
+	fmt.Printf("⚠ Port %d is back: PID %d (%s) was restarted by %s\n",
+		r.Port.Number, r.Port.PID, r.Port.ProcessName, r.Restarter())
+
+	if u := r.Port.Unit; u != nil && u.Restarts() {
+		fmt.Printf("  Stop it with: %s\n", u.StopCommand())
+		return
+	}
+	if r.Supervisor == nil {
+		return
+	}
+	if !killSupervisor {
+		fmt.Printf("  Run again with --kill-supervisor to kill %s instead\n", r.Supervisor.Name)
+		return
+	}
+
+	fmt.Printf("Killing supervisor %s (PID %d)...\n", r.Supervisor.Name, r.Supervisor.PID)
+	supervisorOpts := opts
+	supervisorOpts.Port = 0
+	if result := process.KillProcessContext(ctx, r.Supervisor.PID, supervisorOpts); result.Success {
+		fmt.Printf("✓ %s\n", result.Message)
+	} else {
+		fmt.Fprintf(os.Stderr, "✗ %s\n", result.Message)
+		return
+	}
+
+	// Most supervisors take their children down with them; make sure
+	if process.IsProcessRunning(r.Port.PID) {
+		fmt.Printf("Killing restarted PID %d (%s)...\n", r.Port.PID, r.Port.ProcessName)
+		if result := process.KillProcessContext(ctx, r.Port.PID, opts); result.Success {
+			fmt.Printf("✓ %s\n", result.Message)
+		} else {
+			fmt.Fprintf(os.Stderr, "✗ %s\n", result.Message)
+		}
+	}
+}
+
+// splitContainers separates the ports published by containers, one per
+// container, from the ordinary processes listening on them
+func splitContainers(matches []scanner.Port) ([]scanner.Port, []scanner.Port) {
+	var containers []scanner.Port
+	var rest []scanner.Port
+	seen := make(map[string]bool)
+
+	for _, p := range matches {
+		if p.Container == nil {
+			rest = append(rest, p)
+			continue
+		}
+		if !seen[p.Container.ID] {
+			seen[p.Container.ID] = true
+			containers = append(containers, p)
+		}
+	}
+	return containers, rest
+}
+
+// offerContainerStop asks whether to stop the container publishing a port
+func offerContainerStop(ctx context.Context, p scanner.Port, opts process.KillOptions) {
+	c := p.Container
+	fmt.Printf("Port %d is published by %s container %s (%s).\n", p.Number, c.Runtime, c.Name, c.Image)
+	fmt.Printf("Killing its proxy process would break %s rather than stop the container.\n", c.Runtime)
+	if reason, protected := process.CheckProtected(p.PID, p.Number); protected && !opts.ForceProtected {
+		fmt.Fprintf(os.Stderr, "✗ Refusing to stop container %s: %s (use --force-protected to override)\n", c.Name, reason)
+		return
+	}
+	if !confirm(fmt.Sprintf("Stop container %s instead?", c.Name)) {
+		fmt.Printf("Skipped container %s\n", c.Name)
+		return
+	}
+
+	fmt.Printf("Stopping container %s...\n", c.Name)
+	record := p.StopRecord("container " + c.Name)
+	err := container.Stop(ctx, *c, opts.Timeout)
+	process.RecordStop(record, err)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "✗ %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("✓ Container %s stopped\n", c.Name)
+}
+
+// offerUnitStops offers to stop the systemd units supervising the matched
+// processes and returns the processes that still need to be signalled.
+// Protected processes are left for the kill to refuse.
+func offerUnitStops(ctx context.Context, matches []scanner.Port, opts process.KillOptions) []scanner.Port {
+	var rest []scanner.Port
+	stopped := make(map[string]bool)
+
+	for _, p := range matches {
+		u := p.Unit
+		if u == nil || !u.Restarts() {
+			rest = append(rest, p)
+			continue
+		}
+		if _, protected := process.CheckProtected(p.PID, p.Number); protected && !opts.ForceProtected {
+			rest = append(rest, p)
+			continue
+		}
+		if stopped[u.Name] {
+			continue
+		}
+
+		fmt.Printf("PID %d (%s) on port %d is managed by systemd unit %s; a plain signal will just get it restarted.\n",
+			p.PID, p.ProcessName, p.Number, u.Name)
+		if !confirm(fmt.Sprintf("Run `%s` instead?", u.StopCommand())) {
+			rest = append(rest, p)
+			continue
+		}
+
+		fmt.Printf("Stopping %s...\n", u.Name)
+		record := p.StopRecord("unit " + u.Name)
+		err := systemd.Stop(ctx, *u)
+		process.RecordStop(record, err)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "✗ %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("✓ Unit %s stopped\n", u.Name)
+		stopped[u.Name] = true
+	}
+	return rest
+}
+
+// confirm asks a yes/no question on the terminal, defaulting to no
+func confirm(question string) bool {
+	fmt.Printf("%s [y/N] ", question)
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && answer == "" {
+		fmt.Println()
+		return false
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// printDryRun reports what a kill would do without sending any signal
+func printDryRun(containers []scanner.Port, matches []scanner.Port, opts process.KillOptions) {
+	fmt.Println("Dry run: no signals will be sent")
+	for _, p := range containers {
+		if reason, protected := process.CheckProtected(p.PID, p.Number); protected && !opts.ForceProtected {
+			fmt.Printf("  would refuse to stop %s container %s publishing port %d: %s\n",
+				p.Container.Runtime, p.Container.Name, p.Number, reason)
+			continue
+		}
+		fmt.Printf("  would offer to stop %s container %s (%s) publishing port %d\n",
+			p.Container.Runtime, p.Container.Name, p.Container.Image, p.Number)
+	}
+	if opts.Port == 0 {
+		fmt.Printf("%d processes; portman would ask which of these to kill:\n", len(matches))
+	} else if len(matches) > 1 {
+		fmt.Printf("%d processes on port %d; portman would ask which of these to kill:\n", len(matches), opts.Port)
+	}
+	for _, p := range matches {
+		if reason, protected := process.CheckProtected(p.PID, p.Number); protected {
+			if !opts.ForceProtected {
+				fmt.Printf("  would refuse PID %d (%s) on port %d/%s: %s\n",
+					p.PID, p.ProcessName, p.Number, p.Protocol, reason)
+				continue
+			}
+			fmt.Printf("  PID %d is protected (%s), overridden by --force-protected\n", p.PID, reason)
+		}
+		if p.Unit != nil && p.Unit.Restarts() {
+			fmt.Printf("  would offer `%s` for PID %d (%s) on port %d/%s, otherwise:\n",
+				p.Unit.StopCommand(), p.PID, p.ProcessName, p.Number, p.Protocol)
+		}
+		fmt.Printf("  would kill PID %d (%s) on port %d/%s: %s\n",
+			p.PID, p.ProcessName, p.Number, p.Protocol, opts.Plan())
+		if n := len(p.Connections); n > 0 {
+			fmt.Printf("    %d client(s) still connected (see portman connections %d)\n", n, p.Number)
+		}
+	}
 }
 
 type selectionModel struct {
-	choices  []scanner.Port
-	cursor   int
-	selected map[int]bool
-	portNum  int
-	quitting bool
+	choices   []scanner.Port
+	cursor    int
+	selected  map[int]bool
+	portNum   int
+	quitting  bool
 	cancelled bool
 }
 
@@ -148,10 +475,14 @@ func (m selectionModel) View() string {
 	var s string
 
 	// Title
-	s += titleStyle.Render(fmt.Sprintf("🔍 Select processes to kill on port %d", m.portNum)) + "\n\n"
+	title := "🔍 Select processes to kill"
+	if m.portNum != 0 {
+		title = fmt.Sprintf("🔍 Select processes to kill on port %d", m.portNum)
+	}
+	s += titleStyle.Render(title) + "\n\n"
 
 	// Header
-	header := fmt.Sprintf("%-8s %-10s %-8s %-20s", "SELECT", "PORT", "PID", "PROCESS")
+	header := fmt.Sprintf("%-8s %-10s %-8s %-20s %-8s", "SELECT", "PORT", "PID", "PROCESS", "UPTIME")
 	s += headerStyle.Render(header) + "\n"
 
 	// Process rows
@@ -161,11 +492,12 @@ func (m selectionModel) View() string {
 			checkbox = checkStyle.Render("[✓]")
 		}
 
-		row := fmt.Sprintf("%-8s %-10d %-8d %-20s",
+		row := fmt.Sprintf("%-8s %-10d %-8d %-20s %-8s",
 			checkbox,
 			choice.Number,
 			choice.PID,
-			choice.ProcessName)
+			choice.ProcessName,
+			formatUptime(choice))
 
 		if m.cursor == i {
 			s += selectedRowStyle.Render("▸ "+row) + "\n"
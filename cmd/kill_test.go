@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/NoaTamburrini/portman/internal/scanner"
+)
+
+func TestKillTargets(t *testing.T) {
+	ports := []scanner.Port{
+		{Number: 53, PID: 100, Protocol: "tcp"},
+		{Number: 53, PID: 101, Protocol: "udp"},
+		{Number: 5353, PID: 102, Protocol: "udp"},
+	}
+
+	tests := []struct {
+		proto   string
+		portNum int
+		want    []int
+	}{
+		{"udp", 53, []int{101}},
+		{"tcp", 53, []int{100}},
+		{"all", 53, []int{100, 101}},
+		{"udp", 0, []int{101, 102}},
+		{"tcp", 5353, nil},
+	}
+
+	for _, tt := range tests {
+		var pids []int
+		for _, p := range killTargets(ports, tt.proto, tt.portNum) {
+			pids = append(pids, p.PID)
+		}
+		if len(pids) != len(tt.want) {
+			t.Errorf("killTargets(%s, %d) = PIDs %v, want %v", tt.proto, tt.portNum, pids, tt.want)
+			continue
+		}
+		for i := range pids {
+			if pids[i] != tt.want[i] {
+				t.Errorf("killTargets(%s, %d) = PIDs %v, want %v", tt.proto, tt.portNum, pids, tt.want)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/NoaTamburrini/portman/internal/audit"
+	"github.com/NoaTamburrini/portman/internal/process"
+)
+
+// auditPath returns the configured audit log location
+func auditPath() string {
+	if cfg.Audit.Path != "" {
+		return cfg.Audit.Path
+	}
+	return audit.DefaultPath()
+}
+
+// auditKills records every kill this command makes in the audit log.
+// source says where kills come from: cli, tui or api.
+func auditKills(source string) {
+	if !cfg.Audit.Enabled {
+		return
+	}
+
+	path := auditPath()
+	var warn sync.Once
+	process.ObserveKills(func(r process.KillRecord) {
+		err := audit.Append(path, audit.NewEntry(r, source))
+		// Writing to stderr would garble the TUI
+		if err != nil && source != "tui" {
+			warn.Do(func() {
+				fmt.Fprintf(os.Stderr, "⚠ Could not write audit log: %v\n", err)
+			})
+		}
+	})
+}
+
+func executeKills(args []string) {
+	fs := newFlagSet("kills", "portman kills [--port PORT] [--user USER] [--process NAME] [--outcome OUTCOME] [--since 24h] [--limit 20] [--json]")
+	port := fs.String("port", "", "only kills on this port, service or alias")
+	pid := fs.Int("pid", 0, "only kills of this PID")
+	user := fs.String("user", "", "only kills made by this user")
+	processName := fs.String("process", "", "only kills of processes whose name or command contains this")
+	outcome := fs.String("outcome", "", "only kills with this outcome: graceful, forced, failed, protected, stopped")
+	since := fs.Duration("since", 0, "only kills within this long")
+	limit := fs.Int("limit", 20, "show at most this many of the latest kills; 0 shows all")
+	asJSON := fs.Bool("json", false, "print entries as JSON lines")
+	if positional := parseArgs(fs, args); len(positional) > 0 {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	filter := audit.Filter{
+		PID:     *pid,
+		User:    *user,
+		Process: *processName,
+		Outcome: *outcome,
+	}
+	if *port != "" {
+		portNum, err := resolvePort(*port)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+		filter.Port = portNum
+	}
+	if *since > 0 {
+		filter.Since = time.Now().Add(-*since)
+	}
+
+	path := auditPath()
+	entries, err := audit.Read(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading audit log: %v\n", err)
+		os.Exit(1)
+	}
+
+	var matched []audit.Entry
+	for _, e := range entries {
+		if filter.Match(e) {
+			matched = append(matched, e)
+		}
+	}
+	if *limit > 0 && len(matched) > *limit {
+		matched = matched[len(matched)-*limit:]
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		for _, e := range matched {
+			enc.Encode(e)
+		}
+		return
+	}
+
+	if len(matched) == 0 {
+		fmt.Printf("No kills recorded in %s\n", path)
+		return
+	}
+
+	// Newest first
+	fmt.Printf("%-19s %-16s %-6s %-7s %-8s %-16s %-18s %-10s %s\n",
+		"TIME", "USER", "SOURCE", "PORT", "PID", "PROCESS", "SIGNALS", "OUTCOME", "COMMAND")
+	for i := len(matched) - 1; i >= 0; i-- {
+		e := matched[i]
+		port := "-"
+		if e.Port != 0 {
+			port = fmt.Sprintf("%d", e.Port)
+		}
+		// Stops send no signals; --json has the unit or container's name
+		signals := strings.Join(e.Signals, ",")
+		if kind, _, ok := strings.Cut(e.Stopped, " "); ok {
+			signals = "stop " + kind
+		}
+		fmt.Printf("%-19s %-16s %-6s %-7s %-8d %-16s %-18s %-10s %s\n",
+			e.Time.Local().Format("2006-01-02 15:04:05"),
+			truncate(e.Who(), 16),
+			e.Source,
+			port,
+			e.PID,
+			truncate(orDash(e.Process), 16),
+			orDash(signals),
+			e.Outcome,
+			orDash(e.Command))
+	}
+}
@@ -0,0 +1,246 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/NoaTamburrini/portman/internal/process"
+	"github.com/NoaTamburrini/portman/internal/scanner"
+)
+
+// column is one field of the list table
+type column struct {
+	header string
+	width  int
+	value  func(scanner.Port) string
+}
+
+func executeList(args []string) {
+	fs := newFlagSet("list", "portman list [--json] [--wide] [--timing] [--proto tcp|udp|all] [--older-than 2h] [--project NAME] [--all-netns]")
+	asJSON := fs.Bool("json", false, "print ports as JSON")
+	wide := fs.Bool("wide", false, "include memory, CPU, thread and fd usage")
+	timing := fs.Bool("timing", false, "print how long the scan took, by stage, to stderr")
+	olderThan := fs.Duration("older-than", 0, "only list processes running for at least this long")
+	proto := addProtoFlag(fs)
+	projectFilter := fs.String("project", "", "only list processes running from a matching checkout (name, repository or path)")
+	addScanFlags(fs)
+	if positional := parseArgs(fs, args); len(positional) > 0 {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	ports, t, err := scanner.ScanPortsTimed(context.Background())
+	if *timing {
+		// On stderr, so it can be used with --json
+		fmt.Fprintln(os.Stderr, formatTiming(t))
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error scanning ports: %v\n", err)
+		os.Exit(1)
+	}
+
+	ports = filterProtocol(ports, *proto)
+	if *olderThan > 0 {
+		ports = scanner.OlderThan(ports, *olderThan)
+	}
+	if *projectFilter != "" {
+		ports = scanner.InProject(ports, *projectFilter)
+	}
+
+	sort.Slice(ports, func(i, j int) bool {
+		return ports[i].Number < ports[j].Number
+	})
+
+	// CPU usage needs two samples
+	if *wide {
+		scanner.AddUsage(context.Background(), ports)
+		time.Sleep(scanner.UsageSampleInterval)
+		scanner.AddUsage(context.Background(), ports)
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(ports)
+		return
+	}
+
+	columns := listColumns(ports)
+	if *wide {
+		// Keep COMMAND last
+		last := len(columns) - 1
+		columns = append(columns[:last:last], append(usageColumns(), columns[last])...)
+	}
+	printPortTable(ports, columns)
+}
+
+// listColumns picks the columns to show for the scanned ports
+func listColumns(ports []scanner.Port) []column {
+	columns := []column{
+		{"PORT", 8, func(p scanner.Port) string { return fmt.Sprintf("%d", p.Number) }},
+		{"SERVICE", 14, func(p scanner.Port) string { return orDash(serviceName(p.Number)) }},
+		{"PROTOCOL", 10, func(p scanner.Port) string { return p.Protocol }},
+		{"STATE", 12, func(p scanner.Port) string {
+			// A paused process holds the socket but answers nothing
+			if p.Paused {
+				return "PAUSED"
+			}
+			return orDash(p.State)
+		}},
+		{"PID", 8, func(p scanner.Port) string { return fmt.Sprintf("%d", p.PID) }},
+		{"PROCESS", 20, func(p scanner.Port) string { return p.ProcessName }},
+	}
+
+	if anyPort(ports, func(p scanner.Port) bool { return p.Protocol == "tcp" }) {
+		columns = append(columns, column{"CONNS", 6, func(p scanner.Port) string {
+			if p.Protocol != "tcp" {
+				return "-"
+			}
+			return fmt.Sprintf("%d", len(p.Connections))
+		}})
+	}
+
+	if anyPort(ports, func(p scanner.Port) bool { return !p.StartTime.IsZero() }) {
+		columns = append(columns, column{"UPTIME", 8, formatUptime})
+	}
+
+	if anyPort(ports, func(p scanner.Port) bool { return p.Project != nil }) {
+		columns = append(columns, column{"PROJECT", 24, func(p scanner.Port) string {
+			if p.Project == nil {
+				return "-"
+			}
+			return p.Project.String()
+		}})
+	}
+
+	if anyPort(ports, func(p scanner.Port) bool { return p.Container != nil }) {
+		columns = append(columns, column{"CONTAINER", 24, func(p scanner.Port) string {
+			if p.Container == nil {
+				return "-"
+			}
+			return fmt.Sprintf("%s (%s)", p.Container.Name, p.Container.Image)
+		}})
+	}
+
+	if anyPort(ports, func(p scanner.Port) bool { return p.Namespace != "" }) {
+		columns = append(columns, column{"NAMESPACE", 24, func(p scanner.Port) string {
+			return orDash(p.Namespace)
+		}})
+	}
+
+	if anyPort(ports, func(p scanner.Port) bool { return p.Unit != nil && p.Unit.Restarts() }) {
+		columns = append(columns, column{"UNIT", 24, func(p scanner.Port) string {
+			if p.Unit == nil || !p.Unit.Restarts() {
+				return "-"
+			}
+			return p.Unit.Name
+		}})
+	}
+
+	return append(columns, column{"COMMAND", 0, func(p scanner.Port) string { return p.Command }})
+}
+
+// usageColumns are the resource usage columns shown by --wide
+func usageColumns() []column {
+	usage := func(f func(process.Usage) string) func(scanner.Port) string {
+		return func(p scanner.Port) string {
+			if p.Usage == nil {
+				return "-"
+			}
+			return f(*p.Usage)
+		}
+	}
+
+	return []column{
+		{"RSS", 8, usage(func(u process.Usage) string { return process.FormatBytes(u.RSS) })},
+		{"CPU%", 7, usage(formatCPU)},
+		{"THREADS", 8, usage(func(u process.Usage) string { return countOrDash(u.Threads) })},
+		{"FDS", 6, usage(func(u process.Usage) string { return countOrDash(u.FDs) })},
+	}
+}
+
+// formatCPU formats sampled CPU usage, or "-" before the first sample
+func formatCPU(u process.Usage) string {
+	if !u.CPUSampled {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f", u.CPU)
+}
+
+// formatUptime formats how long a port's owner has been running, or "-"
+// when its start time is unknown
+func formatUptime(p scanner.Port) string {
+	if p.StartTime.IsZero() {
+		return "-"
+	}
+	return process.FormatUptime(p.Uptime())
+}
+
+// formatTiming summarizes how long a scan took, by stage
+func formatTiming(t scanner.Timing) string {
+	return fmt.Sprintf("Scanned in %s (exec %s, parse %s, enrich %s)",
+		roundTiming(t.Total()), roundTiming(t.Exec), roundTiming(t.Parse), roundTiming(t.Enrich))
+}
+
+// roundTiming rounds a duration to about three significant digits
+func roundTiming(d time.Duration) time.Duration {
+	switch {
+	case d >= time.Second:
+		return d.Round(10 * time.Millisecond)
+	case d >= time.Millisecond:
+		return d.Round(10 * time.Microsecond)
+	default:
+		return d.Round(time.Microsecond)
+	}
+}
+
+// countOrDash formats a count, or "-" when it is unknown
+func countOrDash(n int) string {
+	if n == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%d", n)
+}
+
+// anyPort reports whether any port satisfies f, to decide optional columns
+func anyPort(ports []scanner.Port, f func(scanner.Port) bool) bool {
+	for _, p := range ports {
+		if f(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// orDash returns s, or "-" when it is empty
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// printPortTable prints ports as a table; a zero width leaves a column unpadded
+func printPortTable(ports []scanner.Port, columns []column) {
+	row := func(value func(column) string) string {
+		cells := make([]string, len(columns))
+		for i, c := range columns {
+			if c.width == 0 {
+				cells[i] = value(c)
+			} else {
+				cells[i] = fmt.Sprintf("%-*s", c.width, truncate(value(c), c.width))
+			}
+		}
+		return strings.TrimRight(strings.Join(cells, " "), " ")
+	}
+
+	fmt.Println(row(func(c column) string { return c.header }))
+	for _, p := range ports {
+		fmt.Println(row(func(c column) string { return c.value(p) }))
+	}
+}
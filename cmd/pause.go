@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/NoaTamburrini/portman/internal/process"
+	"github.com/NoaTamburrini/portman/internal/scanner"
+)
+
+func executePause(args []string) {
+	fs := newFlagSet("pause", "portman pause [--force-protected] [--proto tcp|udp|all] [--all-netns] <port|service|alias>")
+	forceProtected := fs.Bool("force-protected", false, "allow pausing processes protected by policy")
+	proto := addProtoFlag(fs)
+	addScanFlags(fs)
+	positional := parseArgs(fs, args)
+	if len(positional) != 1 {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	opts := killOptions()
+	opts.ForceProtected = *forceProtected
+	signalPort(context.Background(), positional[0], *proto, "pause", func(p scanner.Port) process.KillResult {
+		opts.Port = p.Number
+		return process.PauseProcess(p.PID, opts)
+	})
+}
+
+func executeResume(args []string) {
+	fs := newFlagSet("resume", "portman resume [--proto tcp|udp|all] [--all-netns] <port|service|alias>")
+	proto := addProtoFlag(fs)
+	addScanFlags(fs)
+	positional := parseArgs(fs, args)
+	if len(positional) != 1 {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	signalPort(context.Background(), positional[0], *proto, "resume", func(p scanner.Port) process.KillResult {
+		return process.ResumeProcess(p.PID)
+	})
+}
+
+// signalPort pauses or resumes every process on a port, once each
+func signalPort(ctx context.Context, arg, proto, verb string, apply func(scanner.Port) process.KillResult) {
+	portNum, err := resolvePort(arg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+
+	ports, err := scanner.ScanPortsContext(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error scanning ports: %v\n", err)
+		os.Exit(1)
+	}
+
+	matches := scanner.FindAllByPort(filterProtocol(ports, proto), portNum)
+	if len(matches) == 0 {
+		fmt.Printf("No process found on port %d\n", portNum)
+		os.Exit(1)
+	}
+
+	failed := false
+	seen := make(map[int]bool)
+	for _, p := range matches {
+		if c := p.Container; c != nil {
+			// Freezing the proxy would hang the runtime rather than the container
+			command := "pause"
+			if verb == "resume" {
+				command = "unpause"
+			}
+			fmt.Fprintf(os.Stderr, "✗ Port %d is published by %s container %s; use `%s %s %s` instead\n",
+				p.Number, c.Runtime, c.Name, c.Runtime, command, c.Name)
+			failed = true
+			continue
+		}
+		if seen[p.PID] {
+			continue
+		}
+		seen[p.PID] = true
+
+		result := apply(p)
+		if result.Success {
+			fmt.Printf("✓ %s (%s on port %d)\n", result.Message, p.ProcessName, p.Number)
+		} else {
+			fmt.Fprintf(os.Stderr, "✗ %s\n", result.Message)
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
@@ -1,28 +1,54 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"time"
 
+	"github.com/NoaTamburrini/portman/internal/monitor"
 	"github.com/NoaTamburrini/portman/internal/tui"
 	"github.com/NoaTamburrini/portman/internal/version"
 )
 
 // Execute is the main entry point for the CLI
 func Execute() {
+	// A broken config must not stop the user from fixing it
+	loadConfig(len(os.Args) > 1 && os.Args[1] == "config")
+
 	// Check for updates in background (non-blocking, cached)
-	version.CheckForUpdate()
+	version.CheckForUpdate(cfg.UpdateCheck.Duration)
 
 	if len(os.Args) > 1 {
 		// Handle subcommands
 		switch os.Args[1] {
 		case "kill":
-			executeKill()
+			executeKill(os.Args[2:])
+		case "connections", "conns":
+			executeConnections(os.Args[2:])
+		case "pause":
+			executePause(os.Args[2:])
+		case "resume":
+			executeResume(os.Args[2:])
+		case "list", "ls":
+			executeList(os.Args[2:])
+		case "status":
+			executeStatus(os.Args[2:])
+		case "kills":
+			executeKills(os.Args[2:])
+		case "serve":
+			executeServe(os.Args[2:])
+		case "exporter":
+			executeExporter(os.Args[2:])
+		case "config":
+			executeConfig(os.Args[2:])
 		case "help", "--help", "-h":
 			printHelp()
 		case "version", "--version", "-v":
 			fmt.Printf("portman v%s\n", version.Version)
 			os.Exit(0)
+		case "--force-protected", "--all-netns", "--proto":
+			executeTUI(os.Args[1:])
 		default:
 			fmt.Printf("Unknown command: %s\n", os.Args[1])
 			printHelp()
@@ -30,6 +56,45 @@ func Execute() {
 		}
 	} else {
 		// No arguments - launch TUI
-		tui.Start()
+		executeTUI(nil)
+	}
+}
+
+// executeTUI launches the interactive TUI with the given flags
+func executeTUI(args []string) {
+	fs := newFlagSet("portman", "portman [--force-protected] [--proto tcp|udp|all] [--all-netns]")
+	forceProtected := fs.Bool("force-protected", false, "allow killing processes protected by policy")
+	proto := addProtoFlag(fs)
+	addScanFlags(fs)
+	if positional := parseArgs(fs, args); len(positional) > 0 {
+		fs.Usage()
+		os.Exit(1)
 	}
+	filterProtocol(nil, *proto)
+
+	kill := killOptions()
+	kill.ForceProtected = *forceProtected
+	auditKills("tui")
+
+	// Where sockets can be watched cheaply, check them every second and
+	// rescan as soon as ports change
+	var mon *monitor.Monitor
+	if monitor.EventDriven() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		mon = monitor.New(time.Second)
+		go mon.Run(ctx)
+	}
+
+	tui.Start(tui.Options{
+		Kill:            kill,
+		RespawnWindow:   cfg.Kill.RespawnWindow.Duration,
+		RefreshInterval: cfg.TUI.RefreshInterval.Duration,
+		ScanTimeout:     cfg.Scanner.Timeout.Duration,
+		Theme:           cfg.TUI.Theme,
+		Keys:            cfg.TUI.Keys,
+		Project:         currentProject(),
+		Protocol:        *proto,
+		Monitor:         mon,
+	})
 }
@@ -0,0 +1,147 @@
+package cmd
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/NoaTamburrini/portman/internal/monitor"
+	"github.com/NoaTamburrini/portman/internal/server"
+)
+
+func executeServe(args []string) {
+	fs := newFlagSet("serve", "portman serve [--listen 127.0.0.1:7070|unix:/path] [--token TOKEN] [--interval 2s] [--allow-force-protected] [--all-netns]")
+	listen := fs.String("listen", cfg.Serve.Listen, `address to listen on, host:port or "unix:/path/to/socket"`)
+	token := fs.String("token", "", "token required to kill processes (default $PORTMAN_TOKEN or the config file, else generated)")
+	interval := fs.Duration("interval", monitor.DefaultInterval, "how often to rescan for the /events stream, or check for changed sockets on Linux")
+	allowForce := fs.Bool("allow-force-protected", false, "let kill requests set force_protected to override the protection policy")
+	addScanFlags(fs)
+	if positional := parseArgs(fs, args); len(positional) > 0 {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	if *token == "" {
+		*token = os.Getenv("PORTMAN_TOKEN")
+	}
+	if *token == "" {
+		*token = cfg.Serve.Token
+	}
+	if *token == "" {
+		*token = generateToken()
+		fmt.Printf("Generated token for kill requests: %s\n", *token)
+	}
+
+	listener, err := listenOn(*listen)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	auditKills("api")
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	mon := monitor.New(*interval)
+	go mon.Run(ctx)
+
+	srv := &http.Server{
+		Handler: server.New(server.Options{
+			Token:               *token,
+			Kill:                killOptions(),
+			Monitor:             mon,
+			Hosts:               listenHosts(*listen),
+			AuditLog:            metricsAuditLog(),
+			AllowForceProtected: *allowForce,
+		}),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdown)
+	}()
+
+	fmt.Printf("✓ Serving the portman API on %s\n", describeListener(listener))
+	if err := srv.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// listenOn opens a TCP listener, or a unix socket for "unix:/path"
+// addresses. Unix sockets are only accessible to the current user.
+func listenOn(address string) (net.Listener, error) {
+	path, ok := strings.CutPrefix(address, "unix:")
+	if !ok {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, fmt.Errorf("invalid listen address %q: %w", address, err)
+		}
+		if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+			fmt.Fprintf(os.Stderr, "⚠ Listening on %s exposes your processes to the network\n", address)
+		}
+		return net.Listen("tcp", address)
+	}
+
+	// A socket left behind by a previous run would make Listen fail
+	if info, err := os.Stat(path); err == nil && info.Mode()&os.ModeSocket != 0 {
+		if conn, err := net.Dial("unix", path); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("%s is in use by another server", path)
+		}
+		os.Remove(path)
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	return listener, nil
+}
+
+// listenHosts returns the host name in a listen address, which requests
+// may use in their Host header alongside localhost and IP addresses
+func listenHosts(address string) []string {
+	if strings.HasPrefix(address, "unix:") {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(address)
+	if err != nil || host == "" {
+		return nil
+	}
+	return []string{host}
+}
+
+// describeListener formats a listener's address for the startup message
+func describeListener(l net.Listener) string {
+	if l.Addr().Network() == "unix" {
+		return "unix:" + l.Addr().String()
+	}
+	return "http://" + l.Addr().String()
+}
+
+// generateToken returns a random token for a server without one configured
+func generateToken() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		fmt.Fprintf(os.Stderr, "Error generating token: %v\n", err)
+		os.Exit(1)
+	}
+	return hex.EncodeToString(b)
+}
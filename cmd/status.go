@@ -0,0 +1,215 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/NoaTamburrini/portman/internal/health"
+	"github.com/NoaTamburrini/portman/internal/process"
+	"github.com/NoaTamburrini/portman/internal/project"
+	"github.com/NoaTamburrini/portman/internal/scanner"
+)
+
+// serviceStatus is the health of one declared service
+type serviceStatus struct {
+	Service  string       `json:"service"`
+	Port     int          `json:"port"`
+	Protocol string       `json:"protocol"`
+	State    string       `json:"state"` // up, down, squatted, unhealthy
+	Required bool         `json:"required"`
+	PID      int          `json:"pid,omitempty"`
+	Process  string       `json:"process,omitempty"`
+	Command  string       `json:"command,omitempty"`
+	Probe    *probeStatus `json:"probe,omitempty"`
+}
+
+// failing reports whether a status should fail `portman status`: a
+// required service that is down, squatted or unhealthy
+func (s serviceStatus) failing() bool {
+	return s.Required && s.State != "up"
+}
+
+// probeStatus is the outcome of a health probe
+type probeStatus struct {
+	OK        bool    `json:"ok"`
+	LatencyMS float64 `json:"latency_ms"`
+	Detail    string  `json:"detail"`
+}
+
+func executeStatus(args []string) {
+	fs := newFlagSet("status", "portman status [--json] [--probe]")
+	asJSON := fs.Bool("json", false, "print status as JSON")
+	probeAll := fs.Bool("probe", false, "TCP-probe services that don't declare a probe")
+	if positional := parseArgs(fs, args); len(positional) > 0 {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	p := currentProject()
+	if p == nil {
+		fmt.Fprintf(os.Stderr, "%v\n", project.ErrNoProject)
+		os.Exit(1)
+	}
+
+	ports, err := scanner.ScanPorts()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error scanning ports: %v\n", err)
+		os.Exit(1)
+	}
+
+	statuses := make([]serviceStatus, 0, len(p.Services))
+	healthy := true
+	for _, service := range p.Services {
+		status := checkService(p, service, ports, *probeAll)
+		if status.failing() {
+			healthy = false
+		}
+		statuses = append(statuses, status)
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(statuses)
+	} else {
+		printStatusTable(p, statuses)
+	}
+
+	if !healthy {
+		os.Exit(1)
+	}
+}
+
+// checkService works out whether a declared service is up and healthy
+func checkService(p *project.Project, service project.Service, ports []scanner.Port, probeAll bool) serviceStatus {
+	status := serviceStatus{
+		Service:  service.Name,
+		Port:     service.Port,
+		Protocol: service.Protocol,
+		State:    "down",
+		Required: service.Required,
+	}
+
+	// A UDP socket on the same number isn't a TCP service, nor the reverse
+	var matches []scanner.Port
+	for _, m := range scanner.FindAllByPort(ports, service.Port) {
+		if m.Protocol == service.Protocol {
+			matches = append(matches, m)
+		}
+	}
+	if len(matches) == 0 {
+		return status
+	}
+
+	// Prefer the owner that matches the expected command, if several share the port
+	owner, command := matches[0], ownerCommand(matches[0])
+	for _, m := range matches {
+		if c := ownerCommand(m); service.Command != "" && strings.Contains(c, service.Command) {
+			owner, command = m, c
+			break
+		}
+	}
+
+	status.PID = owner.PID
+	status.Process = owner.ProcessName
+	status.Command = command
+	status.State = "up"
+
+	if service.Command != "" {
+		if !strings.Contains(command, service.Command) {
+			status.State = "squatted"
+		}
+	} else if !ownedByProject(p, owner) {
+		status.State = "squatted"
+	}
+
+	probe := service.Probe
+	if probe == "" && probeAll && service.Protocol == "tcp" {
+		probe = "tcp"
+	}
+	if probe != "" && status.State == "up" {
+		result := health.Probe(probe, service.Port, service.Timeout)
+		status.Probe = &probeStatus{
+			OK:        result.OK,
+			LatencyMS: float64(result.Latency.Microseconds()) / 1000,
+			Detail:    result.Detail,
+		}
+		if !result.OK {
+			status.State = "unhealthy"
+		}
+	}
+
+	return status
+}
+
+// ownerCommand returns the full command line of a port's owner, falling
+// back to what the scanner reported
+func ownerCommand(port scanner.Port) string {
+	if cmdline, err := process.Cmdline(port.PID); err == nil && cmdline != "" {
+		return cmdline
+	}
+	return port.Command
+}
+
+// ownedByProject reports whether a port's owner runs from inside the
+// project, giving it the benefit of the doubt when its directory is unknown.
+// Container-published ports are owned by a proxy running from /, so their
+// directory says nothing about the project either.
+func ownedByProject(p *project.Project, owner scanner.Port) bool {
+	if owner.Cwd == "" || owner.Container != nil {
+		return true
+	}
+	return p.Contains(owner.Cwd)
+}
+
+// printStatusTable prints service statuses as a table
+func printStatusTable(p *project.Project, statuses []serviceStatus) {
+	fmt.Printf("Project: %s\n\n", p.Path)
+	fmt.Printf("%-16s %-10s %-10s %-8s %-20s %s\n", "SERVICE", "PORT", "STATE", "PID", "PROCESS", "PROBE")
+
+	for _, s := range statuses {
+		state := s.State
+		if !s.Required && state != "up" {
+			state += "*"
+		}
+
+		pid, name := "-", "-"
+		if s.PID != 0 {
+			pid = fmt.Sprintf("%d", s.PID)
+			name = truncate(s.Process, 20)
+		}
+
+		probe := "-"
+		if s.Probe != nil {
+			if s.Probe.OK {
+				probe = fmt.Sprintf("ok %.1fms (%s)", s.Probe.LatencyMS, s.Probe.Detail)
+			} else {
+				probe = fmt.Sprintf("failed: %s", s.Probe.Detail)
+			}
+		}
+
+		port := fmt.Sprintf("%d", s.Port)
+		if s.Protocol != "tcp" {
+			port += "/" + s.Protocol
+		}
+
+		fmt.Printf("%-16s %-10s %-10s %-8s %-20s %s\n", s.Service, port, state, pid, name, probe)
+	}
+
+	for _, s := range statuses {
+		if !s.Required && s.State != "up" {
+			fmt.Println("\n* optional service, does not affect the exit status")
+			break
+		}
+	}
+}
+
+// truncate shortens s to maxLen characters with an ellipsis
+func truncate(s string, maxLen int) string {
+	if len(s) <= maxLen {
+		return s
+	}
+	return s[:maxLen-3] + "..."
+}
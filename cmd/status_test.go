@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/NoaTamburrini/portman/internal/container"
+	"github.com/NoaTamburrini/portman/internal/project"
+	"github.com/NoaTamburrini/portman/internal/scanner"
+)
+
+func TestOwnedByProject(t *testing.T) {
+	p := &project.Project{Root: "/src/app"}
+
+	tests := []struct {
+		name  string
+		owner scanner.Port
+		want  bool
+	}{
+		{"inside project", scanner.Port{Cwd: "/src/app/api"}, true},
+		{"outside project", scanner.Port{Cwd: "/srv/other"}, false},
+		{"unknown cwd", scanner.Port{}, true},
+		{"container proxy", scanner.Port{Cwd: "/", Container: &container.Container{Name: "db"}}, true},
+	}
+
+	for _, tt := range tests {
+		if got := ownedByProject(p, tt.owner); got != tt.want {
+			t.Errorf("%s: ownedByProject = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCheckService(t *testing.T) {
+	p := &project.Project{Root: "/src/app"}
+
+	// Closed once the port is known, so probes of it fail
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed := l.Addr().(*net.TCPAddr).Port
+	l.Close()
+
+	// PIDs beyond pid_max, so the commands come from the scan
+	ports := []scanner.Port{
+		// A UDP socket that happens to share the TCP service's number
+		{Number: 5432, PID: 1<<30 + 5, Command: "dnsmasq", Cwd: "/", Protocol: "udp", State: scanner.StateUnconnected},
+		{Number: 3000, PID: 1 << 30, Command: "node server.js", Cwd: "/src/app/web", Protocol: "tcp", State: scanner.StateListen},
+		{Number: 8080, PID: 1<<30 + 1, Command: "python3 -m http.server 8080", Cwd: "/home/noa", Protocol: "tcp", State: scanner.StateListen},
+		{Number: 9000, PID: 1<<30 + 2, Command: "nginx: worker process", Cwd: "/", Protocol: "tcp", State: scanner.StateListen},
+		{Number: 9000, PID: 1<<30 + 3, Command: "uvicorn app:main --port 9000", Cwd: "/src/app/api", Protocol: "tcp", State: scanner.StateListen},
+		{Number: closed, PID: 1<<30 + 4, Command: "node worker.js", Cwd: "/src/app/worker", Protocol: "tcp", State: scanner.StateListen},
+	}
+
+	tests := []struct {
+		name    string
+		service project.Service
+		state   string
+		pid     int
+		failing bool
+	}{
+		{"up", project.Service{Name: "web", Port: 3000, Protocol: "tcp", Required: true}, "up", 1 << 30, false},
+		{"down", project.Service{Name: "db", Port: 5433, Protocol: "tcp", Required: true}, "down", 0, true},
+		{"udp socket on the port", project.Service{Name: "db", Port: 5432, Protocol: "tcp", Required: true}, "down", 0, true},
+		{"udp service", project.Service{Name: "dns", Port: 5432, Protocol: "udp", Required: true}, "squatted", 1<<30 + 5, true},
+		{"optional and down", project.Service{Name: "cache", Port: 6379, Protocol: "tcp"}, "down", 0, false},
+		{"squatted from outside the project", project.Service{Name: "api", Port: 8080, Protocol: "tcp", Required: true}, "squatted", 1<<30 + 1, true},
+		{"wrong command", project.Service{Name: "web", Port: 3000, Command: "vite", Protocol: "tcp", Required: true}, "squatted", 1 << 30, true},
+		{"expected command among several owners", project.Service{Name: "api", Port: 9000, Command: "uvicorn", Protocol: "tcp", Required: true}, "up", 1<<30 + 3, false},
+		{"failing probe", project.Service{Name: "worker", Port: closed, Probe: "tcp", Timeout: time.Second, Protocol: "tcp", Required: true}, "unhealthy", 1<<30 + 4, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status := checkService(p, tt.service, ports, false)
+			if status.State != tt.state || status.PID != tt.pid {
+				t.Errorf("checkService() = %s (PID %d), want %s (PID %d)", status.State, status.PID, tt.state, tt.pid)
+			}
+			if status.failing() != tt.failing {
+				t.Errorf("failing() = %v, want %v", status.failing(), tt.failing)
+			}
+		})
+	}
+
+	// --probe TCP-probes services without a probe of their own
+	status := checkService(p, project.Service{Name: "worker", Port: closed, Protocol: "tcp", Required: true}, ports, true)
+	if status.State != "unhealthy" || status.Probe == nil || status.Probe.OK {
+		t.Errorf("checkService() with --probe = %+v", status)
+	}
+}
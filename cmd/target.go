@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/NoaTamburrini/portman/internal/project"
+	"github.com/NoaTamburrini/portman/internal/services"
+)
+
+var (
+	proj       *project.Project
+	projLoaded bool
+)
+
+// serviceName names the service on a port: the project service declared
+// on it, or else its well-known service name
+func serviceName(port int) string {
+	if service, ok := currentProject().ServiceForPort(port); ok {
+		return service.Name
+	}
+	return services.Name(port)
+}
+
+// currentProject finds the project file for the working directory, if any
+func currentProject() *project.Project {
+	if projLoaded {
+		return proj
+	}
+	projLoaded = true
+
+	p, err := project.Find(".")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading project file: %v\n", err)
+		os.Exit(1)
+	}
+	proj = p
+	return proj
+}
+
+// resolvePort turns a port number, project service name, configured
+// alias, or well-known service name into a port number
+func resolvePort(arg string) (int, error) {
+	portNum, err := strconv.Atoi(arg)
+	if err != nil {
+		if service, ok := currentProject().Lookup(arg); ok {
+			portNum = service.Port
+		} else if alias, ok := cfg.Aliases[arg]; ok {
+			portNum = alias
+		} else if wellKnown, ok := services.Port(arg); ok {
+			portNum = wellKnown
+		} else {
+			return 0, fmt.Errorf("invalid port number or unknown service: %s", arg)
+		}
+	}
+
+	if portNum < 1 || portNum > 65535 {
+		return 0, fmt.Errorf("port number must be between 1 and 65535")
+	}
+
+	return portNum, nil
+}
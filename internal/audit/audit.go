@@ -0,0 +1,199 @@
+package audit
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/NoaTamburrini/portman/internal/process"
+)
+
+// Outcomes of a kill, as recorded in Entry.Outcome
+const (
+	Graceful  = "graceful"
+	Forced    = "forced"
+	Failed    = "failed"
+	Protected = "protected"
+	// Stopped processes were stopped through their systemd unit or
+	// container rather than signalled
+	Stopped = "stopped"
+)
+
+// Entry is one line of the audit log
+type Entry struct {
+	Time time.Time `json:"time"`
+	// User ran portman; SudoUser is who ran it through sudo, if anyone
+	User     string `json:"user"`
+	SudoUser string `json:"sudo_user,omitempty"`
+	// Source is where the kill came from: cli, tui or api
+	Source  string   `json:"source"`
+	Port    int      `json:"port,omitempty"`
+	PID     int      `json:"pid"`
+	Process string   `json:"process,omitempty"`
+	Command string   `json:"command,omitempty"`
+	Signals []string `json:"signals,omitempty"`
+	// Escalated is set when SIGKILL had to follow the first signal
+	Escalated bool `json:"escalated,omitempty"`
+	// Stopped names the unit or container stopped instead, as "unit
+	// nginx.service" or "container web"
+	Stopped string `json:"stopped,omitempty"`
+	Outcome string `json:"outcome"`
+	Message string `json:"message"`
+}
+
+// Who returns the name to record for the user running portman
+func (e Entry) Who() string {
+	if e.SudoUser != "" {
+		return e.SudoUser + " (sudo)"
+	}
+	return e.User
+}
+
+// DefaultPath returns the audit log location under the XDG state
+// directory, ~/.local/state/portman/kills.jsonl by default
+func DefaultPath() string {
+	dir := os.Getenv("XDG_STATE_HOME")
+	if dir == "" {
+		home, _ := os.UserHomeDir()
+		dir = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(dir, "portman", "kills.jsonl")
+}
+
+// NewEntry describes a kill for the log
+func NewEntry(record process.KillRecord, source string) Entry {
+	e := Entry{
+		Time:     record.Time,
+		User:     currentUser(),
+		SudoUser: os.Getenv("SUDO_USER"),
+		Source:   source,
+		Port:     record.Port,
+		PID:      record.PID,
+		Process:  record.Name,
+		Command:  record.Command,
+		Signals:  record.Result.Signals,
+		Stopped:  record.Stopped,
+		Message:  record.Result.Message,
+	}
+
+	// Escalation means the first signal wasn't SIGKILL but one was sent,
+	// even if the first signal itself couldn't be delivered
+	e.Escalated = record.Result.Forced && record.Signal != "SIGKILL"
+
+	switch {
+	case record.Result.Protected:
+		e.Outcome = Protected
+	case !record.Result.Success:
+		e.Outcome = Failed
+	case record.Stopped != "":
+		e.Outcome = Stopped
+	case record.Result.Forced:
+		e.Outcome = Forced
+	default:
+		e.Outcome = Graceful
+	}
+	return e
+}
+
+// currentUser names the user running portman
+func currentUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
+
+// Append adds an entry to the log at path, creating it if needed. Each
+// entry is written with a single append so concurrent portman processes
+// sharing a log don't interleave lines.
+func Append(path string, e Entry) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Read loads every entry of the log at path, oldest first. A missing log
+// has no entries.
+func Read(path string) ([]Entry, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			return entries, fmt.Errorf("%s:%d: %w", path, n, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
+
+// Filter selects log entries; zero fields match everything
+type Filter struct {
+	Port  int
+	PID   int
+	User  string
+	Since time.Time
+	// Process matches the process name or command, case-insensitively
+	Process string
+	Outcome string
+}
+
+// Match reports whether an entry passes the filter
+func (f Filter) Match(e Entry) bool {
+	if f.Port != 0 && e.Port != f.Port {
+		return false
+	}
+	if f.PID != 0 && e.PID != f.PID {
+		return false
+	}
+	if f.User != "" && e.User != f.User && e.SudoUser != f.User {
+		return false
+	}
+	if !f.Since.IsZero() && e.Time.Before(f.Since) {
+		return false
+	}
+	if f.Process != "" {
+		needle := strings.ToLower(f.Process)
+		if !strings.Contains(strings.ToLower(e.Process), needle) && !strings.Contains(strings.ToLower(e.Command), needle) {
+			return false
+		}
+	}
+	if f.Outcome != "" && e.Outcome != f.Outcome {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,117 @@
+package audit
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/NoaTamburrini/portman/internal/process"
+)
+
+func TestNewEntry(t *testing.T) {
+	tests := []struct {
+		name      string
+		signal    string
+		stopped   string
+		result    process.KillResult
+		outcome   string
+		escalated bool
+	}{
+		{"graceful", "SIGTERM", "", process.KillResult{Success: true, Signals: []string{"SIGTERM"}}, Graceful, false},
+		{"escalated", "SIGTERM", "", process.KillResult{Success: true, Forced: true, Signals: []string{"SIGTERM", "SIGKILL"}}, Forced, true},
+		// SIGTERM couldn't be delivered, so SIGKILL was sent straight away
+		{"first signal failed", "SIGTERM", "", process.KillResult{Success: true, Forced: true, Signals: []string{"SIGKILL"}}, Forced, true},
+		{"sigkill first", "SIGKILL", "", process.KillResult{Success: true, Forced: true, Signals: []string{"SIGKILL"}}, Forced, false},
+		{"failed", "SIGTERM", "", process.KillResult{Message: "operation not permitted"}, Failed, false},
+		{"protected", "SIGTERM", "", process.KillResult{Protected: true}, Protected, false},
+		{"unit stopped", "", "unit nginx.service", process.KillResult{Success: true}, Stopped, false},
+		{"unit stop failed", "", "unit nginx.service", process.KillResult{Message: "access denied"}, Failed, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record := process.KillRecord{PID: 42, Port: 8080, Signal: tt.signal, Stopped: tt.stopped, Result: tt.result}
+			e := NewEntry(record, "cli")
+			if e.Outcome != tt.outcome || e.Escalated != tt.escalated {
+				t.Errorf("NewEntry() outcome = %s, escalated = %v; want %s, %v", e.Outcome, e.Escalated, tt.outcome, tt.escalated)
+			}
+			if e.PID != 42 || e.Port != 8080 || e.Source != "cli" {
+				t.Errorf("NewEntry() = %+v", e)
+			}
+		})
+	}
+}
+
+func TestAppendRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "kills.jsonl")
+
+	if entries, err := Read(path); err != nil || len(entries) != 0 {
+		t.Fatalf("Read() of a missing log = %v, %v", entries, err)
+	}
+
+	now := time.Now().Truncate(time.Second)
+	want := []Entry{
+		{Time: now, User: "noa", Source: "cli", Port: 8080, PID: 1, Process: "caddy", Outcome: Graceful},
+		{Time: now.Add(time.Minute), User: "sam", Source: "tui", Port: 3000, PID: 2, Outcome: Forced},
+	}
+	for _, e := range want {
+		if err := Append(path, e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].Process != "caddy" || got[1].User != "sam" || !got[1].Time.Equal(want[1].Time) {
+		t.Errorf("Read() = %+v", got)
+	}
+
+	// A corrupt line is reported with its line number
+	f, _ := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	f.WriteString("{not json\n")
+	f.Close()
+	if _, err := Read(path); err == nil {
+		t.Error("Read() accepted a corrupt line")
+	}
+}
+
+func TestFilter(t *testing.T) {
+	now := time.Now()
+	e := Entry{
+		Time:     now.Add(-time.Hour),
+		User:     "root",
+		SudoUser: "noa",
+		Port:     8080,
+		PID:      99,
+		Process:  "caddy",
+		Command:  "/usr/bin/caddy run --config staging.json",
+		Outcome:  Forced,
+	}
+
+	tests := []struct {
+		filter Filter
+		want   bool
+	}{
+		{Filter{}, true},
+		{Filter{Port: 8080}, true},
+		{Filter{Port: 3000}, false},
+		{Filter{User: "noa"}, true},
+		{Filter{User: "root"}, true},
+		{Filter{User: "sam"}, false},
+		{Filter{Process: "STAGING"}, true},
+		{Filter{Process: "nginx"}, false},
+		{Filter{Since: now.Add(-2 * time.Hour)}, true},
+		{Filter{Since: now.Add(-time.Minute)}, false},
+		{Filter{Outcome: Graceful}, false},
+		{Filter{PID: 99, Outcome: Forced}, true},
+	}
+
+	for _, tt := range tests {
+		if got := tt.filter.Match(e); got != tt.want {
+			t.Errorf("%+v.Match() = %v, want %v", tt.filter, got, tt.want)
+		}
+	}
+}
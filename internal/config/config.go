@@ -0,0 +1,307 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/NoaTamburrini/portman/internal/process"
+	"github.com/NoaTamburrini/portman/internal/version"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config holds user preferences loaded from the config file
+type Config struct {
+	Kill        KillConfig     `yaml:"kill"`
+	Scanner     ScannerConfig  `yaml:"scanner"`
+	TUI         TUIConfig      `yaml:"tui"`
+	Protected   Protected      `yaml:"protected"`
+	Aliases     map[string]int `yaml:"aliases"`
+	WellKnown   WellKnown      `yaml:"well_known"`
+	Serve       ServeConfig    `yaml:"serve"`
+	Exporter    ExporterConfig `yaml:"exporter"`
+	Audit       AuditConfig    `yaml:"audit"`
+	UpdateCheck Duration       `yaml:"update_check"`
+}
+
+// WellKnown extends the built-in table of well-known service ports
+type WellKnown struct {
+	// Ports names extra services, overriding built-in names for a port
+	Ports map[string]int `yaml:"ports"`
+	// EtcServices also loads names from /etc/services
+	EtcServices bool `yaml:"etc_services"`
+}
+
+// ServeConfig configures the HTTP API started by `portman serve`
+type ServeConfig struct {
+	// Listen is a host:port or "unix:/path/to/socket"
+	Listen string `yaml:"listen"`
+	// Token authorizes requests that kill processes; PORTMAN_TOKEN
+	// overrides it, and a random one is generated when both are empty
+	Token string `yaml:"token"`
+}
+
+// ExporterConfig configures the Prometheus exporter started by
+// `portman exporter`
+type ExporterConfig struct {
+	// Listen is a host:port or "unix:/path/to/socket"
+	Listen string `yaml:"listen"`
+}
+
+// AuditConfig controls the log of kills read by `portman kills`
+type AuditConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Path is the log file; empty means $XDG_STATE_HOME/portman/kills.jsonl
+	Path string `yaml:"path"`
+}
+
+// KillConfig sets the default kill behaviour
+type KillConfig struct {
+	Signal  string   `yaml:"signal"`
+	Timeout Duration `yaml:"timeout"`
+	// RespawnWindow is how long to watch a killed process's port for a
+	// restarted copy; 0s disables the check
+	RespawnWindow Duration `yaml:"respawn_window"`
+	// DrainTimeout is how long kill --drain waits for clients to disconnect
+	DrainTimeout Duration `yaml:"drain_timeout"`
+}
+
+// ScannerConfig selects how ports are discovered
+type ScannerConfig struct {
+	Backend string `yaml:"backend"`
+	// AllNamespaces also scans other Linux network namespaces
+	AllNamespaces bool `yaml:"all_namespaces"`
+	// Timeout is how long the TUI waits for a scan before giving up
+	Timeout Duration `yaml:"timeout"`
+}
+
+// TUIConfig customises the interactive interface
+type TUIConfig struct {
+	RefreshInterval Duration    `yaml:"refresh_interval"`
+	Theme           Theme       `yaml:"theme"`
+	Keys            KeyBindings `yaml:"keys"`
+}
+
+// Theme holds lipgloss colours (ANSI numbers or hex) for the TUI
+type Theme struct {
+	Primary   string `yaml:"primary"`
+	Secondary string `yaml:"secondary"`
+	Success   string `yaml:"success"`
+	Error     string `yaml:"error"`
+	Muted     string `yaml:"muted"`
+	Selected  string `yaml:"selected"`
+	Highlight string `yaml:"highlight"`
+}
+
+// KeyBindings maps TUI actions to the keys that trigger them. Actions
+// left unset, or bound to an empty list, keep their default keys.
+type KeyBindings struct {
+	Up      Keys `yaml:"up"`
+	Down    Keys `yaml:"down"`
+	Kill    Keys `yaml:"kill"`
+	Refresh Keys `yaml:"refresh"`
+	Filter  Keys `yaml:"filter"`
+	Details Keys `yaml:"details"`
+	// Connections lists the clients connected to the selected port
+	Connections Keys `yaml:"connections"`
+	// Usage toggles the memory, CPU, thread and fd columns
+	Usage Keys `yaml:"usage"`
+	// Sort switches between sorting by port and by uptime
+	Sort Keys `yaml:"sort"`
+	// KillSupervisor kills the process manager that restarted a killed process
+	KillSupervisor Keys `yaml:"kill_supervisor"`
+	// Relaunch starts the last killed process again
+	Relaunch Keys `yaml:"relaunch"`
+	// Pause suspends the selected process and Resume continues it
+	Pause  Keys `yaml:"pause"`
+	Resume Keys `yaml:"resume"`
+	// Cancel stops a running scan, or waiting for a killed process to exit
+	Cancel Keys `yaml:"cancel"`
+	Quit   Keys `yaml:"quit"`
+}
+
+// Keys are the keys bound to an action, written as a list or, for a
+// single key, as a plain string
+type Keys []string
+
+// UnmarshalYAML accepts "kill: x" as well as "kill: [x, enter]"
+func (k *Keys) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		if value.Value == "" {
+			return fmt.Errorf("line %d: empty key binding", value.Line)
+		}
+		*k = Keys{value.Value}
+		return nil
+	}
+
+	var keys []string
+	if err := value.Decode(&keys); err != nil {
+		return fmt.Errorf("line %d: key bindings must be a key or a list of keys", value.Line)
+	}
+	*k = keys
+	return nil
+}
+
+// Protected lists processes that must not be killed, on top of the
+// built-in defaults unless IncludeDefaults is false
+type Protected struct {
+	IncludeDefaults bool     `yaml:"include_defaults"`
+	Names           []string `yaml:"names"`
+	Paths           []string `yaml:"paths"`
+	Users           []string `yaml:"users"`
+	Ports           []int    `yaml:"ports"`
+}
+
+// Duration is a time.Duration written as "2s" or "24h" in the config file
+type Duration struct {
+	time.Duration
+}
+
+// UnmarshalYAML parses a Go duration string
+func (d *Duration) UnmarshalYAML(value *yaml.Node) error {
+	parsed, err := time.ParseDuration(value.Value)
+	if err != nil {
+		return fmt.Errorf("line %d: invalid duration %q", value.Line, value.Value)
+	}
+	d.Duration = parsed
+	return nil
+}
+
+// MarshalYAML writes the duration as a Go duration string
+func (d Duration) MarshalYAML() (interface{}, error) {
+	return d.String(), nil
+}
+
+// DefaultScanTimeout is how long the TUI lets a scan run before giving up,
+// so a slow backend can't hang it
+const DefaultScanTimeout = 30 * time.Second
+
+// Default returns the built-in configuration
+func Default() *Config {
+	return &Config{
+		Kill: KillConfig{
+			Signal:        "TERM",
+			Timeout:       Duration{process.DefaultTimeout},
+			RespawnWindow: Duration{2 * time.Second},
+			DrainTimeout:  Duration{process.DefaultDrainTimeout},
+		},
+		Scanner: ScannerConfig{
+			Backend: "auto",
+			Timeout: Duration{DefaultScanTimeout},
+		},
+		TUI: TUIConfig{
+			Theme: Theme{
+				Primary:   "86",
+				Secondary: "212",
+				Success:   "42",
+				Error:     "196",
+				Muted:     "241",
+				Selected:  "219",
+				Highlight: "235",
+			},
+			Keys: KeyBindings{
+				Up:             []string{"up", "k"},
+				Down:           []string{"down", "j"},
+				Kill:           []string{"enter"},
+				Refresh:        []string{"r"},
+				Filter:         []string{"/"},
+				Details:        []string{"i"},
+				Connections:    []string{"c"},
+				Usage:          []string{"u"},
+				Sort:           []string{"s"},
+				KillSupervisor: []string{"K"},
+				Relaunch:       []string{"R"},
+				Pause:          []string{"z"},
+				Resume:         []string{"Z"},
+				Cancel:         []string{"esc"},
+				Quit:           []string{"q", "ctrl+c"},
+			},
+		},
+		Protected: Protected{
+			IncludeDefaults: true,
+		},
+		Aliases: map[string]int{},
+		WellKnown: WellKnown{
+			Ports: map[string]int{},
+		},
+		Serve: ServeConfig{
+			Listen: "127.0.0.1:7070",
+		},
+		Exporter: ExporterConfig{
+			Listen: "127.0.0.1:9464",
+		},
+		Audit: AuditConfig{
+			Enabled: true,
+		},
+		UpdateCheck: Duration{version.CheckPeriod},
+	}
+}
+
+// Path returns the config file location, honouring PORTMAN_CONFIG and
+// XDG_CONFIG_HOME
+func Path() string {
+	if p := os.Getenv("PORTMAN_CONFIG"); p != "" {
+		return p
+	}
+
+	dir := os.Getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, _ := os.UserHomeDir()
+		dir = filepath.Join(home, ".config")
+	}
+	return filepath.Join(dir, "portman", "config.yaml")
+}
+
+// Load reads the config file, falling back to defaults when it doesn't exist
+func Load() (*Config, error) {
+	cfg := Default()
+
+	data, err := os.ReadFile(Path())
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	// Keys left out of the file keep their default values
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return Default(), fmt.Errorf("invalid config %s: %w", Path(), err)
+	}
+
+	return cfg, nil
+}
+
+// Marshal renders the config as YAML
+func (c *Config) Marshal() ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(c); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), enc.Close()
+}
+
+// WriteDefault creates the config file with default values if it doesn't exist
+func WriteDefault() error {
+	path := Path()
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+
+	data, err := Default().Marshal()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	header := []byte("# portman configuration\n# Durations use Go syntax (2s, 5m, 24h); update_check: 0s disables update checks.\n\n")
+	return os.WriteFile(path, append(header, data...), 0644)
+}
@@ -0,0 +1,170 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// useConfig points Path at a config file in a temporary directory,
+// writing content to it unless it is empty
+func useConfig(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	t.Setenv("PORTMAN_CONFIG", path)
+	if content != "" {
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return path
+}
+
+func TestPath(t *testing.T) {
+	t.Setenv("HOME", "/home/noa")
+
+	t.Setenv("PORTMAN_CONFIG", "")
+	t.Setenv("XDG_CONFIG_HOME", "")
+	if got, want := Path(), "/home/noa/.config/portman/config.yaml"; got != want {
+		t.Errorf("Path() = %s, want %s", got, want)
+	}
+
+	t.Setenv("XDG_CONFIG_HOME", "/xdg")
+	if got, want := Path(), "/xdg/portman/config.yaml"; got != want {
+		t.Errorf("Path() with XDG_CONFIG_HOME = %s, want %s", got, want)
+	}
+
+	t.Setenv("PORTMAN_CONFIG", "/etc/portman.yaml")
+	if got, want := Path(), "/etc/portman.yaml"; got != want {
+		t.Errorf("Path() with PORTMAN_CONFIG = %s, want %s", got, want)
+	}
+}
+
+func TestLoadMissing(t *testing.T) {
+	useConfig(t, "")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(cfg, Default()) {
+		t.Errorf("Load() without a file = %+v, want the defaults", cfg)
+	}
+}
+
+func TestLoadPartial(t *testing.T) {
+	useConfig(t, `
+kill:
+  signal: INT
+scanner:
+  timeout: 5s
+tui:
+  keys:
+    kill: x
+    quit: [Q, ctrl+c]
+    up: []
+aliases:
+  api: 8080
+protected:
+  names: [postgres]
+`)
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defaults := Default()
+
+	// Set keys take the file's values
+	if cfg.Kill.Signal != "INT" || cfg.Scanner.Timeout.Duration != 5*time.Second || cfg.Aliases["api"] != 8080 {
+		t.Errorf("Load() ignored values from the file: %+v", cfg)
+	}
+	if !reflect.DeepEqual(cfg.Protected.Names, []string{"postgres"}) {
+		t.Errorf("Protected.Names = %v", cfg.Protected.Names)
+	}
+
+	// Their siblings keep the defaults
+	if cfg.Kill.Timeout != defaults.Kill.Timeout || cfg.Scanner.Backend != "auto" || !cfg.Protected.IncludeDefaults {
+		t.Errorf("Load() dropped defaults next to set values: %+v", cfg)
+	}
+	if cfg.TUI.Theme != defaults.TUI.Theme || cfg.Audit != defaults.Audit || cfg.UpdateCheck != defaults.UpdateCheck {
+		t.Errorf("Load() dropped defaults of sections missing from the file: %+v", cfg)
+	}
+
+	// Key bindings
+	keys := cfg.TUI.Keys
+	if !reflect.DeepEqual(keys.Kill, Keys{"x"}) {
+		t.Errorf("a single key parsed as %v", keys.Kill)
+	}
+	if !reflect.DeepEqual(keys.Quit, Keys{"Q", "ctrl+c"}) {
+		t.Errorf("a list of keys parsed as %v", keys.Quit)
+	}
+	if len(keys.Up) != 0 {
+		t.Errorf("an empty list parsed as %v", keys.Up)
+	}
+	if !reflect.DeepEqual(keys.Down, defaults.TUI.Keys.Down) {
+		t.Errorf("unset binding = %v, want the default %v", keys.Down, defaults.TUI.Keys.Down)
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		err     string
+	}{
+		{"bad duration", "kill:\n  timeout: soon\n", `line 2: invalid duration "soon"`},
+		{"duration without a unit", "update_check: 24\n", `line 1: invalid duration "24"`},
+		{"alias not a port", "aliases:\n  api: eighty\n", "cannot unmarshal"},
+		{"key binding mapping", "tui:\n  keys:\n    kill:\n      key: x\n", "line 4: key bindings must be a key or a list of keys"},
+		{"empty key binding", "tui:\n  keys:\n    kill: \"\"\n", "line 3: empty key binding"},
+		{"not yaml", "kill: [\n", "invalid config"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useConfig(t, tt.content)
+
+			cfg, err := Load()
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Fatalf("Load() error = %v, want one containing %q", err, tt.err)
+			}
+			// Callers that carry on after an error get usable settings
+			if !reflect.DeepEqual(cfg, Default()) {
+				t.Errorf("Load() returned %+v with the error, want the defaults", cfg)
+			}
+		})
+	}
+}
+
+func TestWriteDefault(t *testing.T) {
+	path := useConfig(t, "")
+
+	if err := WriteDefault(); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("Load() of the written defaults: %v", err)
+	}
+	// Empty lists come back empty rather than nil, so compare as YAML
+	got, _ := cfg.Marshal()
+	want, _ := Default().Marshal()
+	if string(got) != string(want) {
+		t.Errorf("written defaults load as:\n%s\nwant:\n%s", got, want)
+	}
+
+	// An existing file is left alone
+	if err := os.WriteFile(path, []byte("kill:\n  signal: INT\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteDefault(); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "kill:\n  signal: INT\n" {
+		t.Errorf("WriteDefault() overwrote the config:\n%s", data)
+	}
+}
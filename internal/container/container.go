@@ -0,0 +1,217 @@
+package container
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Container is a Docker or Podman container publishing a host port
+type Container struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Image   string `json:"image"`
+	Runtime string `json:"runtime"` // "docker" or "podman"
+	// Socket is the API socket the container was found through
+	Socket string `json:"-"`
+}
+
+// Key identifies a published host port
+type Key struct {
+	Port     int
+	Protocol string // "tcp" or "udp"
+	// IP is the host address the port is published on, "*" for all of them
+	IP string
+}
+
+// Client talks to a Docker-compatible API over a unix socket
+type Client struct {
+	Socket  string
+	Runtime string
+	http    *http.Client
+}
+
+// apiContainer is the subset of GET /containers/json that portman uses
+type apiContainer struct {
+	ID    string   `json:"Id"`
+	Names []string `json:"Names"`
+	Image string   `json:"Image"`
+	Ports []struct {
+		IP          string `json:"IP"`
+		PrivatePort int    `json:"PrivatePort"`
+		PublicPort  int    `json:"PublicPort"`
+		Type        string `json:"Type"`
+	} `json:"Ports"`
+}
+
+// NewClient creates a client for the API socket at path
+func NewClient(socket, runtime string) *Client {
+	transport := &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", socket)
+		},
+	}
+
+	return &Client{
+		Socket:  socket,
+		Runtime: runtime,
+		http:    &http.Client{Transport: transport, Timeout: 2 * time.Second},
+	}
+}
+
+// Detect returns clients for every Docker or Podman socket that exists
+func Detect() []*Client {
+	var clients []*Client
+	seen := make(map[string]bool)
+
+	add := func(socket, runtime string) {
+		if socket == "" || seen[socket] {
+			return
+		}
+		if info, err := os.Stat(socket); err != nil || info.Mode()&os.ModeSocket == 0 {
+			return
+		}
+		seen[socket] = true
+		clients = append(clients, NewClient(socket, runtime))
+	}
+
+	if host := os.Getenv("DOCKER_HOST"); strings.HasPrefix(host, "unix://") {
+		add(strings.TrimPrefix(host, "unix://"), "docker")
+	}
+	add("/var/run/docker.sock", "docker")
+	if home, err := os.UserHomeDir(); err == nil {
+		// Docker Desktop on macOS
+		add(filepath.Join(home, ".docker", "run", "docker.sock"), "docker")
+	}
+	if runtimeDir := os.Getenv("XDG_RUNTIME_DIR"); runtimeDir != "" {
+		add(filepath.Join(runtimeDir, "podman", "podman.sock"), "podman")
+	}
+	add("/run/podman/podman.sock", "podman")
+
+	return clients
+}
+
+// PublishedPorts maps the host ports published by running containers
+func (c *Client) PublishedPorts(ctx context.Context) (map[Key]Container, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+c.Runtime+"/containers/json", nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %s: %w", c.Runtime, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status from %s: %d", c.Runtime, resp.StatusCode)
+	}
+
+	var containers []apiContainer
+	if err := json.NewDecoder(resp.Body).Decode(&containers); err != nil {
+		return nil, fmt.Errorf("invalid response from %s: %w", c.Runtime, err)
+	}
+
+	published := make(map[Key]Container)
+	for _, ac := range containers {
+		info := Container{
+			ID:      ac.ID,
+			Name:    containerName(ac),
+			Image:   ac.Image,
+			Runtime: c.Runtime,
+			Socket:  c.Socket,
+		}
+		for _, p := range ac.Ports {
+			if p.PublicPort == 0 {
+				continue // exposed but not published
+			}
+			key := Key{Port: p.PublicPort, Protocol: strings.ToLower(p.Type), IP: hostIP(p.IP)}
+			published[key] = info
+		}
+	}
+
+	return published, nil
+}
+
+// Stop stops a container, giving it timeout to exit before it is killed
+func (c *Client) Stop(ctx context.Context, id string, timeout time.Duration) error {
+	url := fmt.Sprintf("http://%s/containers/%s/stop?t=%d", c.Runtime, id, int(timeout.Seconds()))
+
+	// Stopping waits for the container, so allow longer than the default
+	client := *c.http
+	client.Timeout = timeout + 10*time.Second
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to stop container: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusNoContent, http.StatusNotModified:
+		return nil
+	case http.StatusNotFound:
+		return fmt.Errorf("container %s not found", shortID(id))
+	default:
+		return fmt.Errorf("failed to stop container %s: %s", shortID(id), resp.Status)
+	}
+}
+
+// PublishedPorts merges published ports from every detected runtime,
+// skipping runtimes that don't respond
+func PublishedPorts(ctx context.Context) map[Key]Container {
+	published := make(map[Key]Container)
+	for _, c := range Detect() {
+		ports, err := c.PublishedPorts(ctx)
+		if err != nil {
+			continue
+		}
+		for k, v := range ports {
+			published[k] = v
+		}
+	}
+	return published
+}
+
+// Stop stops a container through the socket that reported it
+func Stop(ctx context.Context, c Container, timeout time.Duration) error {
+	return NewClient(c.Socket, c.Runtime).Stop(ctx, c.ID, timeout)
+}
+
+// hostIP normalises a published address, showing the unspecified
+// addresses, which Podman reports as "", as "*"
+func hostIP(ip string) string {
+	switch ip {
+	case "", "0.0.0.0", "::":
+		return "*"
+	}
+	return ip
+}
+
+// containerName returns the container's primary name without the leading slash
+func containerName(ac apiContainer) string {
+	if len(ac.Names) > 0 {
+		return strings.TrimPrefix(ac.Names[0], "/")
+	}
+	return shortID(ac.ID)
+}
+
+// shortID abbreviates a container ID the way the docker CLI does
+func shortID(id string) string {
+	if len(id) > 12 {
+		return id[:12]
+	}
+	return id
+}
@@ -0,0 +1,101 @@
+package container
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// fakeAPI serves a minimal Docker API on a unix socket
+func fakeAPI(t *testing.T, handler http.Handler) string {
+	t.Helper()
+
+	socket := filepath.Join(t.TempDir(), "docker.sock")
+	ln, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Skipf("unix sockets unavailable: %v", err)
+	}
+
+	srv := &http.Server{Handler: handler}
+	go srv.Serve(ln)
+	t.Cleanup(func() { srv.Close() })
+
+	return socket
+}
+
+func TestPublishedPorts(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /containers/json", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[
+			{"Id": "4f2a9c1d8e7b6a5f", "Names": ["/web"], "Image": "nginx:latest",
+			 "Ports": [{"IP": "0.0.0.0", "PrivatePort": 80, "PublicPort": 8080, "Type": "tcp"},
+			           {"IP": "::", "PrivatePort": 80, "PublicPort": 8080, "Type": "tcp"},
+			           {"PrivatePort": 443, "Type": "tcp"}]},
+			{"Id": "9b8c7d6e5f4a3b2c", "Names": ["/dns"], "Image": "coredns",
+			 "Ports": [{"IP": "127.0.0.1", "PrivatePort": 53, "PublicPort": 5353, "Type": "udp"}]},
+			{"Id": "1a2b3c4d5e6f7a8b", "Names": ["/db"], "Image": "postgres:16",
+			 "Ports": [{"IP": "", "PrivatePort": 5432, "PublicPort": 15432, "Type": "tcp"}]}
+		]`))
+	})
+
+	client := NewClient(fakeAPI(t, mux), "docker")
+	published, err := client.PublishedPorts(context.Background())
+	if err != nil {
+		t.Fatalf("PublishedPorts: %v", err)
+	}
+
+	if len(published) != 3 {
+		t.Fatalf("got %d published ports, want 3: %v", len(published), published)
+	}
+
+	// 0.0.0.0 and :: publish the same port on every address
+	web, ok := published[Key{Port: 8080, Protocol: "tcp", IP: "*"}]
+	if !ok || web.Name != "web" || web.Image != "nginx:latest" || web.Runtime != "docker" {
+		t.Errorf("port 8080/tcp = %+v, want container web (nginx:latest)", web)
+	}
+
+	if dns, ok := published[Key{Port: 5353, Protocol: "udp", IP: "127.0.0.1"}]; !ok || dns.Name != "dns" {
+		t.Errorf("port 5353/udp on 127.0.0.1 = %+v, want container dns", dns)
+	}
+	if _, ok := published[Key{Port: 5353, Protocol: "udp", IP: "*"}]; ok {
+		t.Error("port 5353/udp published on 127.0.0.1 mapped on every address")
+	}
+
+	// Podman leaves the IP empty for every address
+	if db, ok := published[Key{Port: 15432, Protocol: "tcp", IP: "*"}]; !ok || db.Name != "db" {
+		t.Errorf("port 15432/tcp = %+v, want container db", db)
+	}
+
+	if _, ok := published[Key{Port: 443, Protocol: "tcp", IP: "*"}]; ok {
+		t.Error("unpublished port 443 should not be mapped")
+	}
+}
+
+func TestStop(t *testing.T) {
+	var stopped string
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /containers/{id}/stop", func(w http.ResponseWriter, r *http.Request) {
+		if r.PathValue("id") != "4f2a9c1d8e7b6a5f" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		stopped = r.PathValue("id")
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	socket := fakeAPI(t, mux)
+	c := Container{ID: "4f2a9c1d8e7b6a5f", Name: "web", Runtime: "docker", Socket: socket}
+	if err := Stop(context.Background(), c, time.Second); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	if stopped != c.ID {
+		t.Errorf("stopped %q, want %q", stopped, c.ID)
+	}
+
+	if err := Stop(context.Background(), Container{ID: "missing", Runtime: "docker", Socket: socket}, time.Second); err == nil {
+		t.Error("stopping a missing container should fail")
+	}
+}
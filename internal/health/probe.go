@@ -0,0 +1,92 @@
+package health
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultTimeout bounds a probe when none is configured
+const DefaultTimeout = 2 * time.Second
+
+// Result is the outcome of a health probe
+type Result struct {
+	OK      bool
+	Latency time.Duration
+	Detail  string
+}
+
+// Probe checks a service on a local port. spec is "tcp" for a connect,
+// "http" for GET /, an HTTP path like "/health", or a full URL.
+func Probe(spec string, port int, timeout time.Duration) Result {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	if err := ValidateSpec(spec); err != nil {
+		return Result{Detail: err.Error()}
+	}
+
+	switch {
+	case spec == "tcp":
+		return probeTCP(port, timeout)
+	case spec == "http":
+		return probeHTTP(fmt.Sprintf("http://localhost:%d/", port), timeout)
+	case strings.HasPrefix(spec, "/"):
+		return probeHTTP(fmt.Sprintf("http://localhost:%d%s", port, spec), timeout)
+	default:
+		return probeHTTP(spec, timeout)
+	}
+}
+
+// ValidateSpec reports whether spec is a probe that Probe understands, so
+// project files can be checked when they are loaded
+func ValidateSpec(spec string) error {
+	switch {
+	case spec == "tcp", spec == "http", strings.HasPrefix(spec, "/"):
+		return nil
+	case strings.HasPrefix(spec, "http://"), strings.HasPrefix(spec, "https://"):
+		if u, err := url.Parse(spec); err != nil || u.Host == "" {
+			return fmt.Errorf("invalid probe URL %q", spec)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown probe %q (expected tcp, http, a path or a URL)", spec)
+	}
+}
+
+// probeTCP checks that a TCP connection to the port succeeds
+func probeTCP(port int, timeout time.Duration) Result {
+	start := time.Now()
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort("localhost", strconv.Itoa(port)), timeout)
+	latency := time.Since(start)
+	if err != nil {
+		return Result{Latency: latency, Detail: err.Error()}
+	}
+	conn.Close()
+
+	return Result{OK: true, Latency: latency, Detail: "connected"}
+}
+
+// probeHTTP checks that a GET request returns a non-error status
+func probeHTTP(target string, timeout time.Duration) Result {
+	client := &http.Client{Timeout: timeout}
+
+	start := time.Now()
+	resp, err := client.Get(target)
+	latency := time.Since(start)
+	if err != nil {
+		return Result{Latency: latency, Detail: err.Error()}
+	}
+	defer resp.Body.Close()
+
+	return Result{
+		OK:      resp.StatusCode < 400,
+		Latency: latency,
+		Detail:  resp.Status,
+	}
+}
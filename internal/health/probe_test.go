@@ -0,0 +1,119 @@
+package health
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// serverPort returns the port an httptest server listens on
+func serverPort(t *testing.T, srv *httptest.Server) int {
+	t.Helper()
+	u, err := url.Parse(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	port, err := strconv.Atoi(u.Port())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return port
+}
+
+// closedPort returns a port that nothing listens on
+func closedPort(t *testing.T) int {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := l.Addr().(*net.TCPAddr).Port
+	l.Close()
+	return port
+}
+
+func TestProbeHTTP(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/", "/health":
+			w.WriteHeader(http.StatusOK)
+		case "/slow":
+			time.Sleep(500 * time.Millisecond)
+		default:
+			http.Error(w, "broken", http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+	port := serverPort(t, srv)
+
+	tests := []struct {
+		spec   string
+		ok     bool
+		detail string
+	}{
+		{"http", true, "200 OK"},
+		{"/health", true, "200 OK"},
+		{srv.URL + "/health", true, "200 OK"},
+		{"/ready", false, "503 Service Unavailable"},
+		{srv.URL + "/ready", false, "503"},
+		{"/slow", false, "Timeout"},
+	}
+
+	for _, tt := range tests {
+		r := Probe(tt.spec, port, 100*time.Millisecond)
+		if r.OK != tt.ok || !strings.Contains(r.Detail, tt.detail) {
+			t.Errorf("Probe(%s) = %+v, want ok %v with detail containing %q", tt.spec, r, tt.ok, tt.detail)
+		}
+	}
+
+	if r := Probe("http", closedPort(t), time.Second); r.OK {
+		t.Errorf("Probe(http) of a closed port = %+v", r)
+	}
+}
+
+func TestProbeTCP(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	if r := Probe("tcp", l.Addr().(*net.TCPAddr).Port, time.Second); !r.OK || r.Detail != "connected" {
+		t.Errorf("Probe(tcp) of a listener = %+v", r)
+	}
+	if r := Probe("tcp", closedPort(t), time.Second); r.OK {
+		t.Errorf("Probe(tcp) of a closed port = %+v", r)
+	}
+}
+
+func TestValidateSpec(t *testing.T) {
+	tests := map[string]bool{
+		"tcp":                      true,
+		"http":                     true,
+		"/health":                  true,
+		"http://localhost:8080/":   true,
+		"https://api.test/healthz": true,
+		"":                         false,
+		"grpc":                     false,
+		"health":                   false,
+		"http://":                  false,
+		"ftp://localhost/readme":   false,
+		"https://%zz":              false,
+	}
+
+	for spec, valid := range tests {
+		if err := ValidateSpec(spec); (err == nil) != valid {
+			t.Errorf("ValidateSpec(%q) = %v, want valid %v", spec, err, valid)
+		}
+	}
+
+	// Probe refuses what ValidateSpec rejects without connecting anywhere
+	if r := Probe("grpc", 8080, time.Second); r.OK || !strings.Contains(r.Detail, "unknown probe") {
+		t.Errorf("Probe(grpc) = %+v", r)
+	}
+}
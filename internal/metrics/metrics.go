@@ -0,0 +1,215 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/NoaTamburrini/portman/internal/audit"
+	"github.com/NoaTamburrini/portman/internal/scanner"
+	"github.com/NoaTamburrini/portman/internal/services"
+)
+
+// Exporter serves port metrics in the Prometheus text exposition format,
+// scanning ports on every scrape
+type Exporter struct {
+	// AuditLog, when set, adds portman_kills_total, counting the kills
+	// that every portman process recorded in the audit log at this path
+	AuditLog string
+
+	mu     sync.Mutex
+	scans  uint64
+	errors uint64
+	kills  killCounts
+}
+
+// killCounts caches the kills counted in the audit log until it changes
+type killCounts struct {
+	size     int64
+	modTime  time.Time
+	outcomes map[string]uint64
+}
+
+// New creates an exporter
+func New() *Exporter {
+	return &Exporter{}
+}
+
+// ServeHTTP scans ports and writes the metrics
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	// Concurrent scrapes would only run lsof twice for the same answer
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	start := time.Now()
+	ports, err := scanner.ScanPortsContext(r.Context())
+	duration := time.Since(start)
+
+	e.scans++
+	if err != nil {
+		e.errors++
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	e.write(w, ports, err == nil, duration)
+}
+
+// write renders the metrics for one scan
+func (e *Exporter) write(w io.Writer, ports []scanner.Port, ok bool, duration time.Duration) {
+	success := 0
+	if ok {
+		success = 1
+	}
+	header(w, "portman_scan_success", "gauge", "Whether the last port scan succeeded.")
+	fmt.Fprintf(w, "portman_scan_success %d\n", success)
+	header(w, "portman_scan_duration_seconds", "gauge", "How long the last port scan took.")
+	fmt.Fprintf(w, "portman_scan_duration_seconds %s\n", formatFloat(duration.Seconds()))
+	header(w, "portman_scans_total", "counter", "Port scans run by this exporter.")
+	fmt.Fprintf(w, "portman_scans_total %d\n", e.scans)
+	header(w, "portman_scan_errors_total", "counter", "Port scans that failed.")
+	fmt.Fprintf(w, "portman_scan_errors_total %d\n", e.errors)
+
+	if e.AuditLog != "" {
+		outcomes := e.countKills()
+		header(w, "portman_kills_total", "counter", "Kills recorded in the audit log, by outcome.")
+		for _, outcome := range []string{audit.Graceful, audit.Forced, audit.Failed, audit.Protected, audit.Stopped} {
+			fmt.Fprintf(w, "portman_kills_total{outcome=%q} %d\n", outcome, outcomes[outcome])
+		}
+	}
+
+	if !ok {
+		return
+	}
+
+	var listening []scanner.Port
+	for _, p := range ports {
+		if p.Listening() {
+			listening = append(listening, p)
+		}
+	}
+	sort.Slice(listening, func(i, j int) bool {
+		a, b := listening[i], listening[j]
+		if a.Number != b.Number {
+			return a.Number < b.Number
+		}
+		if a.Protocol != b.Protocol {
+			return a.Protocol < b.Protocol
+		}
+		return a.PID < b.PID
+	})
+
+	// Sockets per process, user and protocol
+	type owner struct{ process, user, protocol string }
+	counts := make(map[owner]int)
+	var owners []owner
+	for _, p := range listening {
+		o := owner{p.ProcessName, p.User, p.Protocol}
+		if counts[o] == 0 {
+			owners = append(owners, o)
+		}
+		counts[o]++
+	}
+	sort.Slice(owners, func(i, j int) bool {
+		a, b := owners[i], owners[j]
+		if a.user != b.user {
+			return a.user < b.user
+		}
+		if a.process != b.process {
+			return a.process < b.process
+		}
+		return a.protocol < b.protocol
+	})
+
+	header(w, "portman_listening_sockets", "gauge", "Listening TCP and unconnected UDP sockets, by process, user and protocol.")
+	for _, o := range owners {
+		fmt.Fprintf(w, "portman_listening_sockets{%s} %d\n",
+			labels("process", o.process, "user", o.user, "protocol", o.protocol), counts[o])
+	}
+
+	header(w, "portman_port_info", "gauge", "Which process owns each listening port; always 1.")
+	for _, p := range listening {
+		project, container, unit := "", "", ""
+		if p.Project != nil {
+			project = p.Project.Name
+		}
+		if p.Container != nil {
+			container = p.Container.Name
+		}
+		if p.Unit != nil {
+			unit = p.Unit.Name
+		}
+		fmt.Fprintf(w, "portman_port_info{%s} 1\n", labels(
+			"port", strconv.Itoa(p.Number),
+			"protocol", p.Protocol,
+			"pid", strconv.Itoa(p.PID),
+			"process", p.ProcessName,
+			"user", p.User,
+			"service", services.Name(p.Number),
+			"project", project,
+			"container", container,
+			"unit", unit,
+			"namespace", p.Namespace,
+		))
+	}
+
+	header(w, "portman_process_start_time_seconds", "gauge", "Start time of each process owning a listening port, as a Unix timestamp.")
+	seen := make(map[int]bool)
+	for _, p := range listening {
+		if seen[p.PID] || p.StartTime.IsZero() {
+			continue
+		}
+		seen[p.PID] = true
+		fmt.Fprintf(w, "portman_process_start_time_seconds{%s} %d\n",
+			labels("pid", strconv.Itoa(p.PID), "process", p.ProcessName, "user", p.User), p.StartTime.Unix())
+	}
+}
+
+// countKills counts the audit log's entries by outcome, rereading the log
+// only when it has changed since the last scrape. A corrupt line ends the
+// count there rather than failing the scrape.
+func (e *Exporter) countKills() map[string]uint64 {
+	info, err := os.Stat(e.AuditLog)
+	if err != nil {
+		// Nothing has been killed yet
+		return nil
+	}
+	if e.kills.outcomes != nil && info.Size() == e.kills.size && info.ModTime().Equal(e.kills.modTime) {
+		return e.kills.outcomes
+	}
+
+	entries, _ := audit.Read(e.AuditLog)
+	outcomes := make(map[string]uint64)
+	for _, entry := range entries {
+		outcomes[entry.Outcome]++
+	}
+	e.kills = killCounts{size: info.Size(), modTime: info.ModTime(), outcomes: outcomes}
+	return outcomes
+}
+
+// header writes the HELP and TYPE lines of a metric
+func header(w io.Writer, name, kind, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+// labelEscaper escapes label values as the text format requires
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// labels formats name/value pairs as a label set
+func labels(pairs ...string) string {
+	parts := make([]string, 0, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		parts = append(parts, fmt.Sprintf(`%s="%s"`, pairs[i], labelEscaper.Replace(pairs[i+1])))
+	}
+	return strings.Join(parts, ",")
+}
+
+// formatFloat formats a sample value
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
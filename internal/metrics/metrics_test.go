@@ -0,0 +1,103 @@
+package metrics
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NoaTamburrini/portman/internal/audit"
+	"github.com/NoaTamburrini/portman/internal/scanner"
+)
+
+func TestWrite(t *testing.T) {
+	ports := []scanner.Port{
+		{Number: 3000, PID: 100, ProcessName: "node", User: "noa", Protocol: "tcp", State: scanner.StateListen},
+		{Number: 3001, PID: 100, ProcessName: "node", User: "noa", Protocol: "tcp", State: scanner.StateListen},
+		{Number: 5353, PID: 200, ProcessName: "avahi", User: "avahi", Protocol: "udp", State: scanner.StateUnconnected},
+		// Connections are not listening sockets
+		{Number: 51234, PID: 100, ProcessName: "node", User: "noa", Protocol: "tcp", State: scanner.StateEstablished},
+	}
+
+	var b strings.Builder
+	e := &Exporter{scans: 3, errors: 1}
+	e.write(&b, ports, true, 250*time.Millisecond)
+	out := b.String()
+
+	for _, want := range []string{
+		"portman_scan_success 1\n",
+		"portman_scan_duration_seconds 0.25\n",
+		"portman_scans_total 3\n",
+		"portman_scan_errors_total 1\n",
+		`portman_listening_sockets{process="node",user="noa",protocol="tcp"} 2` + "\n",
+		`portman_listening_sockets{process="avahi",user="avahi",protocol="udp"} 1` + "\n",
+		`portman_port_info{port="3000",protocol="tcp",pid="100",process="node",user="noa",service="",`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output is missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, `port="51234"`) {
+		t.Errorf("output includes a connection:\n%s", out)
+	}
+	// Without an audit log there are no kills to count
+	if strings.Contains(out, "portman_kills_total") {
+		t.Errorf("output includes kill counts:\n%s", out)
+	}
+
+	b.Reset()
+	e.write(&b, nil, false, 0)
+	if out := b.String(); !strings.Contains(out, "portman_scan_success 0\n") || strings.Contains(out, "portman_port_info{") {
+		t.Errorf("failed scan output:\n%s", out)
+	}
+}
+
+func TestLabels(t *testing.T) {
+	got := labels("process", `my "app"`, "command", "a\\b\nc")
+	want := `process="my \"app\"",command="a\\b\nc"`
+	if got != want {
+		t.Errorf("labels() = %s, want %s", got, want)
+	}
+}
+
+func TestKillCounts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kills.jsonl")
+	e := &Exporter{AuditLog: path}
+
+	var b strings.Builder
+	e.write(&b, nil, false, 0)
+	if out := b.String(); !strings.Contains(out, `portman_kills_total{outcome="graceful"} 0`+"\n") {
+		t.Errorf("output without a log:\n%s", out)
+	}
+
+	for _, outcome := range []string{audit.Graceful, audit.Forced, audit.Graceful, audit.Stopped} {
+		if err := audit.Append(path, audit.Entry{Time: time.Now(), PID: 42, Outcome: outcome}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	b.Reset()
+	e.write(&b, nil, false, 0)
+	out := b.String()
+	for _, want := range []string{
+		"# TYPE portman_kills_total counter\n",
+		`portman_kills_total{outcome="graceful"} 2` + "\n",
+		`portman_kills_total{outcome="forced"} 1` + "\n",
+		`portman_kills_total{outcome="failed"} 0` + "\n",
+		`portman_kills_total{outcome="stopped"} 1` + "\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output is missing %q:\n%s", want, out)
+		}
+	}
+
+	// Kills logged by another portman process are picked up on the next scrape
+	if err := audit.Append(path, audit.Entry{Time: time.Now(), PID: 43, Outcome: audit.Protected}); err != nil {
+		t.Fatal(err)
+	}
+	b.Reset()
+	e.write(&b, nil, false, 0)
+	if out := b.String(); !strings.Contains(out, `portman_kills_total{outcome="protected"} 1`+"\n") {
+		t.Errorf("output after another kill:\n%s", out)
+	}
+}
@@ -0,0 +1,291 @@
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/NoaTamburrini/portman/internal/scanner"
+)
+
+// DefaultInterval is how often a Monitor rescans when none is given
+const DefaultInterval = 2 * time.Second
+
+// Event types
+const (
+	// Open is sent when a process starts listening on a port
+	Open = "open"
+	// Close is sent when a port is no longer held by a process
+	Close = "close"
+)
+
+// Event is a change in the set of ports
+type Event struct {
+	Type string       `json:"type"`
+	Time time.Time    `json:"time"`
+	Port scanner.Port `json:"port"`
+}
+
+// processSettle is how long the monitor waits after processes exec or
+// exit before checking their sockets, so a burst of events causes one check
+const processSettle = 200 * time.Millisecond
+
+// subscriberBuffer is how many events a slow subscriber may fall behind
+// before further events are dropped for it
+const subscriberBuffer = 64
+
+// Monitor rescans ports periodically and sends the changes to subscribers.
+// On Linux it watches the listening sockets through netlink instead, and
+// only rescans when they change.
+type Monitor struct {
+	interval time.Duration
+
+	mu      sync.Mutex
+	ports   []scanner.Port
+	scanned bool
+	err     error
+	subs    map[chan Event]struct{}
+}
+
+// listenSocket identifies a listening socket between netlink dumps
+type listenSocket struct {
+	protocol string
+	port     int
+	inode    uint32
+}
+
+// socketSet is the listening sockets found by a netlink dump
+type socketSet map[listenSocket]struct{}
+
+// equal reports whether two dumps found the same sockets
+func (s socketSet) equal(other socketSet) bool {
+	if len(s) != len(other) {
+		return false
+	}
+	for k := range s {
+		if _, ok := other[k]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// EventDriven reports whether monitors watch the listening sockets through
+// netlink here, rather than rescanning every interval
+func EventDriven() bool {
+	if scanner.AllNamespaces() {
+		return false
+	}
+	_, err := dumpListeners()
+	return err == nil
+}
+
+// New creates a monitor that rescans every interval, or that checks for
+// changed sockets every interval when it can watch them through netlink
+func New(interval time.Duration) *Monitor {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	return &Monitor{
+		interval: interval,
+		subs:     make(map[chan Event]struct{}),
+	}
+}
+
+// Run scans until the context is cancelled. The first scan only records
+// the current ports; later scans send an event for each change.
+func (m *Monitor) Run(ctx context.Context) {
+	// Netlink only sees portman's own network namespace
+	if !scanner.AllNamespaces() {
+		if sockets, err := dumpListeners(); err == nil {
+			m.watch(ctx, sockets)
+			return
+		}
+	}
+
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		m.scan(ctx)
+		select {
+		case <-ctx.Done():
+			m.closeSubscribers()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// watch rescans only when the listening sockets change, checking them
+// with a cheap netlink dump every interval and as soon as processes exec
+// or exit
+func (m *Monitor) watch(ctx context.Context, last socketSet) {
+	m.scan(ctx)
+
+	processes := watchProcesses(ctx)
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+	var settle <-chan time.Time
+
+	for {
+		select {
+		case <-ctx.Done():
+			m.closeSubscribers()
+			return
+		case <-processes:
+			if settle == nil {
+				settle = time.After(processSettle)
+			}
+			continue
+		case <-settle:
+			settle = nil
+		case <-ticker.C:
+		}
+
+		sockets, err := dumpListeners()
+		if err == nil && sockets.equal(last) {
+			continue
+		}
+		// A failed dump falls back to a full scan
+		m.scan(ctx)
+		if err == nil {
+			last = sockets
+		}
+	}
+}
+
+// scan rescans the ports and publishes what changed since the last scan
+func (m *Monitor) scan(ctx context.Context) {
+	ports, err := scanner.ScanPortsContext(ctx)
+	if ctx.Err() != nil {
+		// Stopping isn't a failed scan
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.err = err
+	if err != nil {
+		return
+	}
+
+	if m.scanned {
+		for _, e := range Diff(m.ports, ports, time.Now()) {
+			m.publish(e)
+		}
+	}
+	m.ports = ports
+	m.scanned = true
+}
+
+// publish sends an event to every subscriber without blocking on slow ones.
+// Callers hold m.mu.
+func (m *Monitor) publish(e Event) {
+	for ch := range m.subs {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+// Subscribe returns a channel of port changes and a function that stops
+// the subscription. The channel is closed when the monitor stops.
+func (m *Monitor) Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBuffer)
+
+	m.mu.Lock()
+	m.subs[ch] = struct{}{}
+	m.mu.Unlock()
+
+	return ch, func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if _, ok := m.subs[ch]; ok {
+			delete(m.subs, ch)
+			close(ch)
+		}
+	}
+}
+
+// closeSubscribers ends every subscription
+func (m *Monitor) closeSubscribers() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for ch := range m.subs {
+		delete(m.subs, ch)
+		close(ch)
+	}
+}
+
+// Ports returns the ports found by the latest scan, sorted by port number,
+// and the error of the latest scan if it failed
+func (m *Monitor) Ports() ([]scanner.Port, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ports := append([]scanner.Port{}, m.ports...)
+	sortPorts(ports)
+	return ports, m.err
+}
+
+// key identifies a socket across scans
+func key(p scanner.Port) string {
+	return fmt.Sprintf("%s-%d-%d-%s", p.Protocol, p.Number, p.PID, p.Namespace)
+}
+
+// index keys ports for diffing
+func index(ports []scanner.Port) map[string]scanner.Port {
+	indexed := make(map[string]scanner.Port, len(ports))
+	for _, p := range ports {
+		indexed[key(p)] = p
+	}
+	return indexed
+}
+
+// Diff lists the ports opened and closed between two scans, closes
+// first, each sorted by port number
+func Diff(before, after []scanner.Port, now time.Time) []Event {
+	was, is := index(before), index(after)
+
+	var closed, opened []scanner.Port
+	for k, p := range was {
+		if _, ok := is[k]; !ok {
+			closed = append(closed, p)
+		}
+	}
+	for k, p := range is {
+		if _, ok := was[k]; !ok {
+			opened = append(opened, p)
+		}
+	}
+	sortPorts(closed)
+	sortPorts(opened)
+
+	events := make([]Event, 0, len(closed)+len(opened))
+	for _, p := range closed {
+		events = append(events, Event{Type: Close, Time: now, Port: p})
+	}
+	for _, p := range opened {
+		events = append(events, Event{Type: Open, Time: now, Port: p})
+	}
+	return events
+}
+
+// sortPorts orders ports by number, then protocol and PID
+func sortPorts(ports []scanner.Port) {
+	sort.Slice(ports, func(i, j int) bool {
+		a, b := ports[i], ports[j]
+		if a.Number != b.Number {
+			return a.Number < b.Number
+		}
+		if a.Protocol != b.Protocol {
+			return a.Protocol < b.Protocol
+		}
+		return a.PID < b.PID
+	})
+}
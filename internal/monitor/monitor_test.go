@@ -0,0 +1,67 @@
+package monitor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/NoaTamburrini/portman/internal/scanner"
+)
+
+func TestDiff(t *testing.T) {
+	before := []scanner.Port{
+		{Number: 3000, PID: 100, Protocol: "tcp"},
+		{Number: 5432, PID: 200, Protocol: "tcp"},
+		{Number: 8080, PID: 300, Protocol: "tcp"},
+	}
+	after := []scanner.Port{
+		{Number: 3000, PID: 101, Protocol: "tcp"}, // restarted under a new PID
+		{Number: 5353, PID: 400, Protocol: "udp"},
+		{Number: 5432, PID: 200, Protocol: "tcp"},
+	}
+
+	now := time.Now()
+	events := Diff(before, after, now)
+
+	want := []struct {
+		typ  string
+		port int
+		pid  int
+	}{
+		{Close, 3000, 100},
+		{Close, 8080, 300},
+		{Open, 3000, 101},
+		{Open, 5353, 400},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("Diff() = %d events, want %d: %+v", len(events), len(want), events)
+	}
+	for i, w := range want {
+		e := events[i]
+		if e.Type != w.typ || e.Port.Number != w.port || e.Port.PID != w.pid || !e.Time.Equal(now) {
+			t.Errorf("event %d = %s %d/%d, want %s %d/%d", i, e.Type, e.Port.Number, e.Port.PID, w.typ, w.port, w.pid)
+		}
+	}
+
+	if events := Diff(after, after, now); len(events) != 0 {
+		t.Errorf("Diff() of identical scans = %+v, want none", events)
+	}
+}
+
+func TestSubscribe(t *testing.T) {
+	m := New(time.Second)
+	events, unsubscribe := m.Subscribe()
+
+	m.mu.Lock()
+	m.publish(Event{Type: Open, Port: scanner.Port{Number: 3000}})
+	m.mu.Unlock()
+
+	if e := <-events; e.Port.Number != 3000 {
+		t.Errorf("received %+v, want port 3000", e)
+	}
+
+	unsubscribe()
+	unsubscribe() // safe to call twice
+	if _, ok := <-events; ok {
+		t.Error("channel still open after unsubscribe")
+	}
+}
@@ -0,0 +1,226 @@
+//go:build linux
+
+package monitor
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+)
+
+// Netlink message types and sizes not defined by the syscall package,
+// from linux/sock_diag.h, linux/inet_diag.h and linux/cn_proc.h
+const (
+	sockDiagByFamily = 20
+
+	nlmsgHeaderLen = 16
+	// inetDiagReqLen is the size of struct inet_diag_req_v2
+	inetDiagReqLen = 56
+	// inetDiagMsgLen is the size of struct inet_diag_msg
+	inetDiagMsgLen = 72
+
+	tcpStateListen = 10
+	tcpStateClose  = 7
+
+	// The proc connector's netlink group and message ID
+	cnIdxProc = 1
+	cnValProc = 1
+	// cnMsgLen is the size of struct cn_msg, before its payload
+	cnMsgLen          = 20
+	procCnMcastListen = 1
+	procEventExec     = 0x00000002
+	procEventExit     = 0x80000000
+)
+
+// processRecvTimeout is how long watchProcesses blocks reading events
+// before checking whether it should stop
+const processRecvTimeout = time.Second
+
+// diagQueries are the sockets dumped by dumpListeners: listening TCP and
+// unconnected UDP sockets, over IPv4 and IPv6
+var diagQueries = []struct {
+	family   uint8
+	protocol uint8
+	name     string
+	states   uint32
+}{
+	{syscall.AF_INET, syscall.IPPROTO_TCP, "tcp", 1 << tcpStateListen},
+	{syscall.AF_INET6, syscall.IPPROTO_TCP, "tcp", 1 << tcpStateListen},
+	{syscall.AF_INET, syscall.IPPROTO_UDP, "udp", 1 << tcpStateClose},
+	{syscall.AF_INET6, syscall.IPPROTO_UDP, "udp", 1 << tcpStateClose},
+}
+
+// dumpListeners lists the listening sockets in portman's network namespace
+// through NETLINK_SOCK_DIAG, which costs a single round trip to the kernel
+// per query instead of a process per scan
+func dumpListeners() (socketSet, error) {
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_DGRAM|syscall.SOCK_CLOEXEC, syscall.NETLINK_INET_DIAG)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sock_diag socket: %w", err)
+	}
+	defer syscall.Close(fd)
+
+	sockets := make(socketSet)
+	buf := make([]byte, 8*os.Getpagesize())
+	for seq, q := range diagQueries {
+		req := diagRequest(q.family, q.protocol, q.states, uint32(seq+1))
+		if err := syscall.Sendto(fd, req, 0, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK}); err != nil {
+			return nil, fmt.Errorf("failed to query sock_diag: %w", err)
+		}
+
+		for done := false; !done; {
+			n, _, err := syscall.Recvfrom(fd, buf, 0)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read sock_diag: %w", err)
+			}
+			done, err = parseDiagMessages(buf[:n], q.name, sockets)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return sockets, nil
+}
+
+// diagRequest encodes a SOCK_DIAG_BY_FAMILY dump request for the sockets
+// of a family and protocol in the given TCP states
+func diagRequest(family, protocol uint8, states, seq uint32) []byte {
+	b := make([]byte, nlmsgHeaderLen+inetDiagReqLen)
+	binary.NativeEndian.PutUint32(b[0:4], uint32(len(b)))
+	binary.NativeEndian.PutUint16(b[4:6], sockDiagByFamily)
+	binary.NativeEndian.PutUint16(b[6:8], syscall.NLM_F_REQUEST|syscall.NLM_F_DUMP)
+	binary.NativeEndian.PutUint32(b[8:12], seq)
+
+	req := b[nlmsgHeaderLen:]
+	req[0] = family
+	req[1] = protocol
+	binary.NativeEndian.PutUint32(req[4:8], states)
+	return b
+}
+
+// parseDiagMessages adds the sockets in a sock_diag reply to a set, and
+// reports whether the reply ends the dump
+func parseDiagMessages(b []byte, protocol string, sockets socketSet) (bool, error) {
+	msgs, err := syscall.ParseNetlinkMessage(b)
+	if err != nil {
+		return false, fmt.Errorf("failed to parse sock_diag reply: %w", err)
+	}
+
+	for _, msg := range msgs {
+		switch msg.Header.Type {
+		case syscall.NLMSG_DONE:
+			return true, nil
+		case syscall.NLMSG_ERROR:
+			if len(msg.Data) < 4 {
+				return false, fmt.Errorf("sock_diag failed")
+			}
+			errno := -int32(binary.NativeEndian.Uint32(msg.Data[0:4]))
+			return false, fmt.Errorf("sock_diag failed: %w", syscall.Errno(errno))
+		}
+		if len(msg.Data) < inetDiagMsgLen {
+			continue
+		}
+		// The socket ID's ports and addresses are in network byte order
+		sockets[listenSocket{
+			protocol: protocol,
+			port:     int(binary.BigEndian.Uint16(msg.Data[4:6])),
+			inode:    binary.NativeEndian.Uint32(msg.Data[68:72]),
+		}] = struct{}{}
+	}
+	return false, nil
+}
+
+// watchProcesses subscribes to the kernel's proc connector and signals
+// the returned channel whenever a process execs or exits, until the
+// context is cancelled. Subscribing needs CAP_NET_ADMIN; without it the
+// channel is nil.
+func watchProcesses(ctx context.Context) <-chan struct{} {
+	fd, err := syscall.Socket(syscall.AF_NETLINK, syscall.SOCK_DGRAM|syscall.SOCK_CLOEXEC, syscall.NETLINK_CONNECTOR)
+	if err != nil {
+		return nil
+	}
+	if err := subscribeProcesses(fd); err != nil {
+		syscall.Close(fd)
+		return nil
+	}
+
+	events := make(chan struct{}, 1)
+	go func() {
+		defer syscall.Close(fd)
+		buf := make([]byte, os.Getpagesize())
+		for ctx.Err() == nil {
+			// Reads time out so the loop notices the context is done
+			n, _, err := syscall.Recvfrom(fd, buf, 0)
+			switch err {
+			case nil:
+			case syscall.EAGAIN, syscall.EINTR:
+				continue
+			case syscall.ENOBUFS:
+				// Events were dropped during a burst, some of which may
+				// have been execs or exits
+				n = 0
+			default:
+				return
+			}
+			if n == 0 || processChanged(buf[:n]) {
+				select {
+				case events <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+	return events
+}
+
+// subscribeProcesses binds a NETLINK_CONNECTOR socket to the proc
+// connector's group and asks the kernel to send it process events
+func subscribeProcesses(fd int) error {
+	tv := syscall.NsecToTimeval(processRecvTimeout.Nanoseconds())
+	if err := syscall.SetsockoptTimeval(fd, syscall.SOL_SOCKET, syscall.SO_RCVTIMEO, &tv); err != nil {
+		return err
+	}
+	// Port ID 0 lets the kernel pick one that doesn't clash with other
+	// netlink sockets in this process
+	if err := syscall.Bind(fd, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK, Groups: cnIdxProc}); err != nil {
+		return err
+	}
+	return syscall.Sendto(fd, listenRequest(), 0, &syscall.SockaddrNetlink{Family: syscall.AF_NETLINK})
+}
+
+// listenRequest encodes the PROC_CN_MCAST_LISTEN message that turns on
+// process events for a socket
+func listenRequest() []byte {
+	b := make([]byte, nlmsgHeaderLen+cnMsgLen+4)
+	binary.NativeEndian.PutUint32(b[0:4], uint32(len(b)))
+	binary.NativeEndian.PutUint16(b[4:6], syscall.NLMSG_DONE)
+
+	msg := b[nlmsgHeaderLen:]
+	binary.NativeEndian.PutUint32(msg[0:4], cnIdxProc)
+	binary.NativeEndian.PutUint32(msg[4:8], cnValProc)
+	binary.NativeEndian.PutUint16(msg[16:18], 4)
+	binary.NativeEndian.PutUint32(msg[cnMsgLen:], procCnMcastListen)
+	return b
+}
+
+// processChanged reports whether proc connector messages include a
+// process exec or exit, the events that can open or close ports
+func processChanged(b []byte) bool {
+	msgs, err := syscall.ParseNetlinkMessage(b)
+	if err != nil {
+		return false
+	}
+	for _, msg := range msgs {
+		if len(msg.Data) < cnMsgLen+4 {
+			continue
+		}
+		switch binary.NativeEndian.Uint32(msg.Data[cnMsgLen:]) {
+		case procEventExec, procEventExit:
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,109 @@
+package monitor
+
+import (
+	"encoding/binary"
+	"errors"
+	"net"
+	"syscall"
+	"testing"
+)
+
+// netlinkMessage encodes a netlink message with a payload
+func netlinkMessage(typ uint16, payload []byte) []byte {
+	b := make([]byte, nlmsgHeaderLen+len(payload))
+	binary.NativeEndian.PutUint32(b[0:4], uint32(len(b)))
+	binary.NativeEndian.PutUint16(b[4:6], typ)
+	copy(b[nlmsgHeaderLen:], payload)
+	return b
+}
+
+// diagMessage encodes an inet_diag_msg for a socket
+func diagMessage(port uint16, inode uint32) []byte {
+	msg := make([]byte, inetDiagMsgLen)
+	msg[0] = syscall.AF_INET
+	msg[1] = tcpStateListen
+	binary.BigEndian.PutUint16(msg[4:6], port)
+	binary.NativeEndian.PutUint32(msg[68:72], inode)
+	return netlinkMessage(sockDiagByFamily, msg)
+}
+
+func TestParseDiagMessages(t *testing.T) {
+	sockets := make(socketSet)
+	reply := append(diagMessage(8080, 41001), diagMessage(5432, 41002)...)
+
+	done, err := parseDiagMessages(reply, "tcp", sockets)
+	if err != nil || done {
+		t.Fatalf("parseDiagMessages() = %v, %v; want false, nil", done, err)
+	}
+	want := socketSet{
+		{protocol: "tcp", port: 8080, inode: 41001}: {},
+		{protocol: "tcp", port: 5432, inode: 41002}: {},
+	}
+	if !sockets.equal(want) {
+		t.Errorf("sockets = %v, want %v", sockets, want)
+	}
+
+	if done, err := parseDiagMessages(netlinkMessage(syscall.NLMSG_DONE, make([]byte, 4)), "tcp", sockets); !done || err != nil {
+		t.Errorf("parseDiagMessages(NLMSG_DONE) = %v, %v; want true, nil", done, err)
+	}
+
+	errno := make([]byte, 4)
+	code := -int32(syscall.EACCES)
+	binary.NativeEndian.PutUint32(errno, uint32(code))
+	if _, err := parseDiagMessages(netlinkMessage(syscall.NLMSG_ERROR, errno), "tcp", sockets); !errors.Is(err, syscall.EACCES) {
+		t.Errorf("parseDiagMessages(NLMSG_ERROR) error = %v, want EACCES", err)
+	}
+}
+
+func TestProcessChanged(t *testing.T) {
+	event := func(what uint32) []byte {
+		payload := make([]byte, cnMsgLen+16)
+		binary.NativeEndian.PutUint32(payload[cnMsgLen:], what)
+		return netlinkMessage(syscall.NLMSG_DONE, payload)
+	}
+
+	tests := []struct {
+		name string
+		what uint32
+		want bool
+	}{
+		{"fork", 0x00000001, false},
+		{"exec", procEventExec, true},
+		{"uid", 0x00000004, false},
+		{"exit", procEventExit, true},
+	}
+	for _, tt := range tests {
+		if got := processChanged(event(tt.what)); got != tt.want {
+			t.Errorf("processChanged(%s) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDumpListeners(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := ln.Addr().(*net.TCPAddr).Port
+
+	listening := func() bool {
+		sockets, err := dumpListeners()
+		if err != nil {
+			t.Skipf("sock_diag unavailable: %v", err)
+		}
+		for s := range sockets {
+			if s.protocol == "tcp" && s.port == port {
+				return true
+			}
+		}
+		return false
+	}
+
+	if !listening() {
+		t.Errorf("dumpListeners() is missing port %d", port)
+	}
+	ln.Close()
+	if listening() {
+		t.Errorf("dumpListeners() still lists port %d after it closed", port)
+	}
+}
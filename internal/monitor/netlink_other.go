@@ -0,0 +1,19 @@
+//go:build !linux
+
+package monitor
+
+import (
+	"context"
+	"errors"
+)
+
+// dumpListeners needs NETLINK_SOCK_DIAG, which only Linux has
+func dumpListeners() (socketSet, error) {
+	return nil, errors.New("sock_diag is only available on Linux")
+}
+
+// watchProcesses needs the Linux proc connector; elsewhere there are no
+// process events to watch
+func watchProcesses(ctx context.Context) <-chan struct{} {
+	return nil
+}
@@ -0,0 +1,216 @@
+package process
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// Info describes a running process
+type Info struct {
+	PID  int
+	PPID int
+	Name string
+	Exe  string
+	User string
+}
+
+// Lookup gathers information about a process by PID
+func Lookup(pid int) (Info, error) {
+	switch runtime.GOOS {
+	case "linux":
+		return lookupProc(pid)
+	case "darwin":
+		return lookupPS(pid)
+	default:
+		return Info{PID: pid}, nil
+	}
+}
+
+// lookupProc reads process information from /proc on Linux
+func lookupProc(pid int) (Info, error) {
+	dir := filepath.Join("/proc", strconv.Itoa(pid))
+	comm, err := os.ReadFile(filepath.Join(dir, "comm"))
+	if err != nil {
+		return Info{}, fmt.Errorf("process %d not found: %w", pid, err)
+	}
+
+	info := Info{
+		PID:  pid,
+		Name: strings.TrimSpace(string(comm)),
+	}
+
+	// exe is unreadable for other users' processes without privileges
+	if exe, err := os.Readlink(filepath.Join(dir, "exe")); err == nil {
+		info.Exe = exe
+	}
+
+	if status, err := os.ReadFile(filepath.Join(dir, "status")); err == nil {
+		for _, line := range strings.Split(string(status), "\n") {
+			fields := strings.Fields(line)
+			if len(fields) < 2 {
+				continue
+			}
+			switch fields[0] {
+			case "Uid:":
+				info.User = usernameForUID(fields[1])
+			case "PPid:":
+				info.PPID, _ = strconv.Atoi(fields[1])
+			}
+		}
+	}
+
+	return info, nil
+}
+
+// lookupPS reads process information from ps on macOS
+func lookupPS(pid int) (Info, error) {
+	cmd := exec.Command("ps", "-o", "ppid=,user=,comm=", "-p", strconv.Itoa(pid))
+	output, err := cmd.Output()
+	if err != nil {
+		return Info{}, fmt.Errorf("process %d not found: %w", pid, err)
+	}
+
+	fields := strings.Fields(strings.TrimSpace(string(output)))
+	if len(fields) < 3 {
+		return Info{}, fmt.Errorf("process %d not found", pid)
+	}
+
+	// comm is the full executable path on macOS and may contain spaces
+	ppid, _ := strconv.Atoi(fields[0])
+	exe := strings.Join(fields[2:], " ")
+	return Info{
+		PID:  pid,
+		PPID: ppid,
+		Name: filepath.Base(exe),
+		Exe:  exe,
+		User: fields[1],
+	}, nil
+}
+
+// usernameForUID resolves a numeric UID, falling back to the number itself
+func usernameForUID(uid string) string {
+	if u, err := user.LookupId(uid); err == nil {
+		return u.Username
+	}
+	return uid
+}
+
+// Cwds returns the working directories of several processes at once.
+// Processes whose working directory can't be read are left out.
+func Cwds(ctx context.Context, pids []int) map[int]string {
+	cwds := make(map[int]string, len(pids))
+	switch runtime.GOOS {
+	case "linux":
+		for _, pid := range pids {
+			if cwd, err := os.Readlink(filepath.Join("/proc", strconv.Itoa(pid), "cwd")); err == nil {
+				cwds[pid] = cwd
+			}
+		}
+	case "darwin":
+		if len(pids) == 0 {
+			return cwds
+		}
+		list := make([]string, len(pids))
+		for i, pid := range pids {
+			list[i] = strconv.Itoa(pid)
+		}
+		// lsof exits non-zero if any PID is gone, but still reports the rest
+		output, _ := exec.CommandContext(ctx, "lsof", "-a", "-d", "cwd", "-p", strings.Join(list, ","), "-Fpn").Output()
+		return parseLsofCwds(string(output))
+	}
+	return cwds
+}
+
+// parseLsofCwds parses lsof -Fpn output, where a "p<pid>" line starts
+// each process and an "n<path>" line names its file
+func parseLsofCwds(output string) map[int]string {
+	cwds := make(map[int]string)
+	pid := 0
+	for _, line := range strings.Split(output, "\n") {
+		switch {
+		case strings.HasPrefix(line, "p"):
+			pid, _ = strconv.Atoi(line[1:])
+		case strings.HasPrefix(line, "n") && pid != 0:
+			cwds[pid] = line[1:]
+		}
+	}
+	return cwds
+}
+
+// Users returns the users owning several processes at once. Processes
+// that are gone are left out.
+func Users(ctx context.Context, pids []int) map[int]string {
+	users := make(map[int]string, len(pids))
+	switch runtime.GOOS {
+	case "linux":
+		names := make(map[string]string)
+		for _, pid := range pids {
+			status, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "status"))
+			if err != nil {
+				continue
+			}
+			for _, line := range strings.Split(string(status), "\n") {
+				fields := strings.Fields(line)
+				if len(fields) < 2 || fields[0] != "Uid:" {
+					continue
+				}
+				name, ok := names[fields[1]]
+				if !ok {
+					name = usernameForUID(fields[1])
+					names[fields[1]] = name
+				}
+				users[pid] = name
+				break
+			}
+		}
+	case "darwin":
+		if len(pids) == 0 {
+			return users
+		}
+		list := make([]string, len(pids))
+		for i, pid := range pids {
+			list[i] = strconv.Itoa(pid)
+		}
+		// ps exits non-zero if any PID is gone, but still reports the rest
+		output, _ := exec.CommandContext(ctx, "ps", "-o", "pid=,user=", "-p", strings.Join(list, ",")).Output()
+		for _, line := range strings.Split(string(output), "\n") {
+			fields := strings.Fields(line)
+			if len(fields) != 2 {
+				continue
+			}
+			if pid, err := strconv.Atoi(fields[0]); err == nil {
+				users[pid] = fields[1]
+			}
+		}
+	}
+	return users
+}
+
+// Cmdline returns the full command line of a process
+func Cmdline(pid int) (string, error) {
+	switch runtime.GOOS {
+	case "linux":
+		data, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "cmdline"))
+		if err != nil {
+			return "", err
+		}
+		// Arguments are NUL-separated with a trailing NUL
+		return strings.TrimSpace(strings.ReplaceAll(string(data), "\x00", " ")), nil
+	case "darwin":
+		cmd := exec.Command("ps", "-o", "command=", "-p", strconv.Itoa(pid))
+		output, err := cmd.Output()
+		if err != nil {
+			return "", fmt.Errorf("failed to execute ps: %w", err)
+		}
+		return strings.TrimSpace(string(output)), nil
+	default:
+		return "", fmt.Errorf("command line lookup not supported on %s", runtime.GOOS)
+	}
+}
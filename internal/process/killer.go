@@ -1,20 +1,163 @@
 package process
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 	"syscall"
 	"time"
 )
 
+// DefaultTimeout is how long KillProcess waits for a graceful exit before forcing
+const DefaultTimeout = 2 * time.Second
+
+// KillOptions controls how KillProcess treats a process
+type KillOptions struct {
+	// Port the process was found on, checked against port protection rules
+	Port int
+	// ForceProtected kills the process even if the policy protects it
+	ForceProtected bool
+	// Signal is sent first; defaults to SIGTERM
+	Signal syscall.Signal
+	// Timeout is how long to wait before escalating to SIGKILL; defaults to DefaultTimeout
+	Timeout time.Duration
+	// Drain, when set, waits for clients to disconnect after the first
+	// signal before the timeout starts
+	Drain *Drain
+}
+
+// DefaultDrainTimeout is how long a drain waits for clients to disconnect
+const DefaultDrainTimeout = 30 * time.Second
+
+// drainInterval is how often a drain counts the connected clients
+const drainInterval = time.Second
+
+// Drain waits for the clients of a server to disconnect before a kill
+// escalates, giving it the chance to finish their requests
+type Drain struct {
+	// Connections counts the clients still connected. It is called about
+	// once a second, so callers can report progress from it.
+	Connections func(ctx context.Context) (int, error)
+	// Timeout is how long to wait for the count to reach zero; defaults
+	// to DefaultDrainTimeout
+	Timeout time.Duration
+}
+
+// timeout returns how long to wait for clients to disconnect
+func (d *Drain) timeout() time.Duration {
+	if d.Timeout <= 0 {
+		return DefaultDrainTimeout
+	}
+	return d.Timeout
+}
+
+// signal returns the initial signal to send
+func (o KillOptions) signal() syscall.Signal {
+	if o.Signal == 0 {
+		return syscall.SIGTERM
+	}
+	return o.Signal
+}
+
+// timeout returns how long to wait before escalating
+func (o KillOptions) timeout() time.Duration {
+	if o.Timeout <= 0 {
+		return DefaultTimeout
+	}
+	return o.Timeout
+}
+
+// Plan describes the signals KillProcess sends, for dry runs
+func (o KillOptions) Plan() string {
+	if o.signal() == syscall.SIGKILL {
+		return "SIGKILL"
+	}
+	if o.Drain != nil {
+		return fmt.Sprintf("%s, wait up to %s for clients to disconnect, then SIGKILL if still running after %s",
+			SignalName(o.signal()), o.Drain.timeout(), o.timeout())
+	}
+	return fmt.Sprintf("%s, then SIGKILL if still running after %s", SignalName(o.signal()), o.timeout())
+}
+
 // KillResult represents the result of a kill operation
 type KillResult struct {
-	Success bool
+	Success   bool
+	Protected bool
+	// Forced is set when the process had to be sent SIGKILL
+	Forced bool
+	// Signals lists the signals delivered, in order
+	Signals []string
 	Message string
 }
 
+// KillRecord describes a finished call to KillProcess, or a process
+// stopped through its unit or container, for auditing
+type KillRecord struct {
+	Time    time.Time
+	PID     int
+	Port    int
+	Name    string
+	Command string
+	// Signal is the signal KillProcess was asked to send first
+	Signal string
+	// Stopped names what was stopped instead of signalling the process,
+	// such as "unit nginx.service" or "container web"
+	Stopped string
+	Result  KillResult
+}
+
+// killObserver is told about every kill, when set
+var killObserver func(KillRecord)
+
+// ObserveKills registers a function called after every KillProcess and
+// RecordStop, or removes it when fn is nil
+func ObserveKills(fn func(KillRecord)) {
+	killObserver = fn
+}
+
+// RecordStop tells the kill observer about a process that was stopped
+// through its systemd unit or container rather than signalled. The record
+// describes the process as it was before the stop.
+func RecordStop(record KillRecord, err error) {
+	if killObserver == nil {
+		return
+	}
+	record.Result = KillResult{Success: err == nil, Message: "Stopped " + record.Stopped}
+	if err != nil {
+		record.Result.Message = err.Error()
+	}
+	killObserver(record)
+}
+
 // KillProcess kills a process by PID with graceful fallback
-func KillProcess(pid int) KillResult {
+func KillProcess(pid int, opts KillOptions) KillResult {
+	return KillProcessContext(context.Background(), pid, opts)
+}
+
+// KillProcessContext kills a process like KillProcess, but stops waiting
+// for it to exit, without escalating, once the context is done
+func KillProcessContext(ctx context.Context, pid int, opts KillOptions) KillResult {
+	record := KillRecord{Time: time.Now(), PID: pid, Port: opts.Port, Signal: SignalName(opts.signal())}
+	if killObserver != nil {
+		// The process is gone afterwards, so describe it first
+		if info, err := Lookup(pid); err == nil {
+			record.Name = info.Name
+		}
+		record.Command, _ = Cmdline(pid)
+	}
+
+	result := killProcess(ctx, pid, opts)
+
+	if killObserver != nil {
+		record.Result = result
+		killObserver(record)
+	}
+	return result
+}
+
+// killProcess does the work of KillProcessContext
+func killProcess(ctx context.Context, pid int, opts KillOptions) KillResult {
 	if pid <= 0 {
 		return KillResult{
 			Success: false,
@@ -22,6 +165,14 @@ func KillProcess(pid int) KillResult {
 		}
 	}
 
+	if reason, protected := CheckProtected(pid, opts.Port); protected && !opts.ForceProtected {
+		return KillResult{
+			Success:   false,
+			Protected: true,
+			Message:   fmt.Sprintf("Refusing to kill PID %d: %s (use --force-protected to override)", pid, reason),
+		}
+	}
+
 	process, err := os.FindProcess(pid)
 	if err != nil {
 		return KillResult{
@@ -30,19 +181,29 @@ func KillProcess(pid int) KillResult {
 		}
 	}
 
-	// Try graceful kill first (SIGTERM)
-	err = process.Signal(syscall.SIGTERM)
+	var sent []string
+	send := func(sig syscall.Signal) error {
+		err := process.Signal(sig)
+		if err == nil {
+			sent = append(sent, SignalName(sig))
+		}
+		return err
+	}
+
+	// Try graceful kill first (SIGTERM unless configured otherwise)
+	sig := opts.signal()
+	err = send(sig)
 	if err != nil {
-		// If SIGTERM fails, might be permission issue or process already dead
-		if err.Error() == "os: process already finished" {
+		// If the signal fails, might be permission issue or process already dead
+		if errors.Is(err, os.ErrProcessDone) {
 			return KillResult{
 				Success: true,
 				Message: "Process already terminated",
 			}
 		}
 
-		// Try force kill immediately if SIGTERM fails
-		err = process.Signal(syscall.SIGKILL)
+		// Try force kill immediately if the graceful signal fails
+		err = send(syscall.SIGKILL)
 		if err != nil {
 			return KillResult{
 				Success: false,
@@ -52,40 +213,82 @@ func KillProcess(pid int) KillResult {
 
 		return KillResult{
 			Success: true,
+			Forced:  true,
+			Signals: sent,
+			Message: "Process killed (forced)",
+		}
+	}
+
+	if sig == syscall.SIGKILL {
+		return KillResult{
+			Success: true,
+			Forced:  true,
+			Signals: sent,
 			Message: "Process killed (forced)",
 		}
 	}
 
+	// A paused process only handles the signal once it's continued
+	if Paused(ctx, []int{pid})[pid] {
+		resume(pid)
+	}
+
 	// Wait a bit to see if process terminates gracefully
-	terminated := waitForTermination(pid, 2*time.Second)
+	terminated := waitForTermination(ctx, pid, opts.timeout(), opts.Drain)
+
+	if !terminated && ctx.Err() != nil && IsProcessRunning(pid) {
+		return KillResult{
+			Success: false,
+			Signals: sent,
+			Message: fmt.Sprintf("Stopped waiting for PID %d to exit after %s", pid, SignalName(sig)),
+		}
+	}
 
 	if !terminated {
 		// Process didn't terminate, force kill
-		err = process.Signal(syscall.SIGKILL)
+		err = send(syscall.SIGKILL)
+		if errors.Is(err, os.ErrProcessDone) {
+			// It exited just as the timeout expired
+			return KillResult{
+				Success: true,
+				Signals: sent,
+				Message: "Process terminated",
+			}
+		}
 		if err != nil {
 			return KillResult{
 				Success: false,
+				Signals: sent,
 				Message: fmt.Sprintf("Failed to force kill process: %v", err),
 			}
 		}
 
 		return KillResult{
 			Success: true,
+			Forced:  true,
+			Signals: sent,
 			Message: "Process killed (forced after timeout)",
 		}
 	}
 
 	return KillResult{
 		Success: true,
+		Signals: sent,
 		Message: "Process terminated gracefully",
 	}
 }
 
-// waitForTermination waits for a process to terminate
-func waitForTermination(pid int, timeout time.Duration) bool {
+// waitForTermination waits for a process to terminate, or for the context
+// to be done. With a drain, the timeout only starts once its clients have
+// disconnected or the drain times out.
+func waitForTermination(ctx context.Context, pid int, timeout time.Duration, drain *Drain) bool {
+	if drain != nil && waitForDrain(ctx, pid, drain) {
+		return true
+	}
+
 	deadline := time.Now().Add(timeout)
 
-	for time.Now().Before(deadline) {
+	for time.Now().Before(deadline) && ctx.Err() == nil {
 		process, err := os.FindProcess(pid)
 		if err != nil {
 			return true // Process not found means it terminated
@@ -97,12 +300,50 @@ func waitForTermination(pid int, timeout time.Duration) bool {
 			return true // Process doesn't exist anymore
 		}
 
-		time.Sleep(100 * time.Millisecond)
+		sleep(ctx, 100*time.Millisecond)
 	}
 
 	return false
 }
 
+// waitForDrain waits for a process's clients to disconnect, and reports
+// whether the process exited meanwhile
+func waitForDrain(ctx context.Context, pid int, drain *Drain) bool {
+	deadline := time.Now().Add(drain.timeout())
+	nextCount := time.Now()
+
+	for ctx.Err() == nil {
+		if !IsProcessRunning(pid) {
+			return true
+		}
+
+		now := time.Now()
+		if !now.Before(nextCount) {
+			// A count that fails won't succeed later; stop waiting on it
+			if n, err := drain.Connections(ctx); err != nil || n == 0 {
+				return false
+			}
+			nextCount = now.Add(drainInterval)
+		}
+		if !now.Before(deadline) {
+			return false
+		}
+
+		sleep(ctx, 100*time.Millisecond)
+	}
+	return false
+}
+
+// sleep pauses for d, or until the context is done
+func sleep(ctx context.Context, d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+	case <-timer.C:
+	}
+}
+
 // IsProcessRunning checks if a process is still running
 func IsProcessRunning(pid int) bool {
 	process, err := os.FindProcess(pid)
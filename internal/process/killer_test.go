@@ -0,0 +1,93 @@
+package process
+
+import (
+	"context"
+	"os/exec"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// startStubborn starts a process that ignores SIGTERM
+func startStubborn(t *testing.T) int {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("needs sh")
+	}
+
+	cmd := exec.Command("sh", "-c", "trap '' TERM; while :; do sleep 0.05; done")
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { cmd.Process.Kill() })
+	go cmd.Wait()
+
+	// Let the shell set its trap
+	time.Sleep(100 * time.Millisecond)
+	return cmd.Process.Pid
+}
+
+func TestKillDrain(t *testing.T) {
+	pid := startStubborn(t)
+
+	// Clients disconnect on the second check
+	counts := []int{1, 0}
+	var checks int
+	drain := &Drain{
+		Connections: func(context.Context) (int, error) {
+			n := counts[min(checks, len(counts)-1)]
+			checks++
+			return n, nil
+		},
+		Timeout: 10 * time.Second,
+	}
+
+	start := time.Now()
+	result := KillProcess(pid, KillOptions{Timeout: 200 * time.Millisecond, Drain: drain})
+	if !result.Success || !result.Forced {
+		t.Errorf("KillProcess() = %+v, want a forced kill", result)
+	}
+	if checks != 2 {
+		t.Errorf("counted connections %d times, want 2", checks)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("kill took %s; the drain should end when clients disconnect", elapsed)
+	}
+}
+
+func TestKillDrainTimeout(t *testing.T) {
+	pid := startStubborn(t)
+
+	drain := &Drain{
+		Connections: func(context.Context) (int, error) { return 3, nil },
+		Timeout:     300 * time.Millisecond,
+	}
+
+	start := time.Now()
+	result := KillProcess(pid, KillOptions{Timeout: 200 * time.Millisecond, Drain: drain})
+	if !result.Success || !result.Forced {
+		t.Errorf("KillProcess() = %+v, want a forced kill", result)
+	}
+	if elapsed := time.Since(start); elapsed < 500*time.Millisecond {
+		t.Errorf("kill took %s, want the drain timeout and the kill timeout", elapsed)
+	}
+}
+
+func TestKillProcessContextCancel(t *testing.T) {
+	pid := startStubborn(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	result := KillProcessContext(ctx, pid, KillOptions{Timeout: 10 * time.Second})
+	if result.Success || result.Forced {
+		t.Errorf("KillProcessContext() = %+v, want it to give up without SIGKILL", result)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("kill took %s; it should stop waiting when the context is done", elapsed)
+	}
+	if !IsProcessRunning(pid) {
+		t.Error("the process was killed after the context was cancelled")
+	}
+}
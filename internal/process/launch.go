@@ -0,0 +1,223 @@
+package process
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// Launch is how a process was started, captured before killing it so the
+// same command can be started again
+type Launch struct {
+	// Argv is the command line, Argv[0] being the program
+	Argv []string
+	// Exe is the program's path, which Argv[0] may not name; empty means
+	// Argv[0] is looked up on the process's PATH
+	Exe string
+	// Dir is the working directory
+	Dir string
+	// Env is the environment; Start refuses to run without it
+	Env []string
+	// Credential is the user and groups the process ran as, nil for
+	// portman's own
+	Credential *Credential
+}
+
+// Credential is the user and groups a process runs as
+type Credential struct {
+	UID    uint32
+	GID    uint32
+	Groups []uint32
+}
+
+// String formats the command line for display
+func (l Launch) String() string {
+	return strings.Join(l.Argv, " ")
+}
+
+// CaptureLaunch records the command line, program, working directory,
+// environment and owner of a running process. Only Linux exposes another
+// process's environment, so other systems can't capture processes.
+func CaptureLaunch(pid int) (Launch, error) {
+	if runtime.GOOS != "linux" {
+		return Launch{}, fmt.Errorf("capturing processes is not supported on %s", runtime.GOOS)
+	}
+	return captureProc(pid)
+}
+
+// captureProc reads a process's launch details from /proc
+func captureProc(pid int) (Launch, error) {
+	dir := filepath.Join("/proc", strconv.Itoa(pid))
+
+	cmdline, err := os.ReadFile(filepath.Join(dir, "cmdline"))
+	if err != nil {
+		return Launch{}, fmt.Errorf("process %d not found: %w", pid, err)
+	}
+	argv := splitNul(cmdline)
+	if len(argv) == 0 {
+		// Kernel threads have no command line
+		return Launch{}, fmt.Errorf("process %d has no command line", pid)
+	}
+
+	// exe, cwd and environ are only readable for our own processes
+	// without privileges
+	exe, err := os.Readlink(filepath.Join(dir, "exe"))
+	if err != nil {
+		return Launch{}, fmt.Errorf("can't read executable of process %d: %w", pid, err)
+	}
+	// A rebuilt program replaces the one that was running, which is the
+	// one to start again
+	exe = strings.TrimSuffix(exe, " (deleted)")
+
+	cwd, err := os.Readlink(filepath.Join(dir, "cwd"))
+	if err != nil {
+		return Launch{}, fmt.Errorf("can't read working directory of process %d: %w", pid, err)
+	}
+
+	environ, err := os.ReadFile(filepath.Join(dir, "environ"))
+	if err != nil {
+		return Launch{}, fmt.Errorf("can't read environment of process %d: %w", pid, err)
+	}
+
+	status, err := os.ReadFile(filepath.Join(dir, "status"))
+	if err != nil {
+		return Launch{}, fmt.Errorf("process %d not found: %w", pid, err)
+	}
+	cred, err := parseCredential(string(status))
+	if err != nil {
+		return Launch{}, fmt.Errorf("can't read owner of process %d: %w", pid, err)
+	}
+
+	// An empty environment is still a captured one
+	env := splitNul(environ)
+	if env == nil {
+		env = []string{}
+	}
+	return Launch{Argv: argv, Exe: exe, Dir: cwd, Env: env, Credential: cred}, nil
+}
+
+// parseCredential reads the real user and group IDs and supplementary
+// groups from /proc/<pid>/status
+func parseCredential(status string) (*Credential, error) {
+	var cred Credential
+	var haveUID, haveGID bool
+	for _, line := range strings.Split(status, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "Uid:", "Gid:":
+			if len(fields) < 2 {
+				continue
+			}
+			id, err := strconv.ParseUint(fields[1], 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("bad %s line %q", strings.TrimSuffix(fields[0], ":"), line)
+			}
+			if fields[0] == "Uid:" {
+				cred.UID, haveUID = uint32(id), true
+			} else {
+				cred.GID, haveGID = uint32(id), true
+			}
+		case "Groups:":
+			for _, g := range fields[1:] {
+				id, err := strconv.ParseUint(g, 10, 32)
+				if err != nil {
+					return nil, fmt.Errorf("bad Groups line %q", line)
+				}
+				cred.Groups = append(cred.Groups, uint32(id))
+			}
+		}
+	}
+	if !haveUID || !haveGID {
+		return nil, errors.New("no Uid or Gid line")
+	}
+	return &cred, nil
+}
+
+// splitNul splits NUL-terminated strings
+func splitNul(data []byte) []string {
+	data = bytes.TrimRight(data, "\x00")
+	if len(data) == 0 {
+		return nil
+	}
+	return strings.Split(string(data), "\x00")
+}
+
+// Start runs the command again in its directory and environment, as the
+// user it ran as and detached from portman so it outlives it, and returns
+// the new PID
+func (l Launch) Start() (int, error) {
+	if len(l.Argv) == 0 {
+		return 0, errors.New("no command to start")
+	}
+	// portman's own environment would start it with the wrong settings
+	if l.Env == nil {
+		return 0, fmt.Errorf("can't relaunch %s: its environment wasn't captured", l.Argv[0])
+	}
+
+	cmd := exec.Command(l.Argv[0], l.Argv[1:]...)
+	if l.Exe != "" {
+		cmd.Path = l.Exe
+		cmd.Err = nil
+	} else if path, err := lookPath(l.Argv[0], l.Dir, l.Env); err == nil {
+		// Look the program up on the process's own PATH, as its shell did
+		cmd.Path = path
+		cmd.Err = nil
+	}
+	cmd.Dir = l.Dir
+	cmd.Env = l.Env
+	// Output would otherwise land in the TUI
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = nil, nil, nil
+	detach(cmd)
+	if l.Credential != nil {
+		runAs(cmd, *l.Credential)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return 0, fmt.Errorf("failed to start %s: %w", l.Argv[0], err)
+	}
+	pid := cmd.Process.Pid
+
+	// Reap the process when it exits rather than leaving a zombie while
+	// portman runs
+	go cmd.Wait()
+	return pid, nil
+}
+
+// lookPath resolves a program name the way a shell with the given
+// environment in dir would
+func lookPath(name, dir string, env []string) (string, error) {
+	if strings.Contains(name, string(os.PathSeparator)) {
+		if !filepath.IsAbs(name) {
+			name = filepath.Join(dir, name)
+		}
+		return name, nil
+	}
+
+	for _, kv := range env {
+		value, ok := strings.CutPrefix(kv, "PATH=")
+		if !ok {
+			continue
+		}
+		for _, d := range filepath.SplitList(value) {
+			if !filepath.IsAbs(d) {
+				d = filepath.Join(dir, d)
+			}
+			path := filepath.Join(d, name)
+			if info, err := os.Stat(path); err == nil && !info.IsDir() && info.Mode()&0111 != 0 {
+				return path, nil
+			}
+		}
+		return "", exec.ErrNotFound
+	}
+
+	return exec.LookPath(name)
+}
@@ -0,0 +1,132 @@
+package process
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSplitNul(t *testing.T) {
+	tests := map[string][]string{
+		"":                         nil,
+		"\x00":                     nil,
+		"node\x00server.js\x00":    {"node", "server.js"},
+		"sh\x00-c\x00echo  hi\x00": {"sh", "-c", "echo  hi"},
+		"a\x00\x00b\x00":           {"a", "", "b"},
+	}
+
+	for input, want := range tests {
+		if got := splitNul([]byte(input)); !reflect.DeepEqual(got, want) {
+			t.Errorf("splitNul(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestParseCredential(t *testing.T) {
+	status := "Name:\tnode\nUid:\t1000\t1000\t1000\t1000\nGid:\t100\t100\t100\t100\nGroups:\t27 100 \n"
+	cred, err := parseCredential(status)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Credential{UID: 1000, GID: 100, Groups: []uint32{27, 100}}
+	if !reflect.DeepEqual(cred, want) {
+		t.Errorf("parseCredential() = %+v, want %+v", cred, want)
+	}
+
+	if _, err := parseCredential("Name:\tnode\n"); err == nil {
+		t.Error("parseCredential() without a Uid line succeeded")
+	}
+}
+
+func TestCaptureLaunch(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("needs /proc")
+	}
+
+	launch, err := CaptureLaunch(os.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exe, _ := os.Executable(); launch.Exe != exe {
+		t.Errorf("Exe = %q, want %q", launch.Exe, exe)
+	}
+	if launch.Env == nil {
+		t.Error("Env wasn't captured")
+	}
+	if c := launch.Credential; c == nil || int(c.UID) != os.Getuid() || int(c.GID) != os.Getgid() {
+		t.Errorf("Credential = %+v, want UID %d and GID %d", c, os.Getuid(), os.Getgid())
+	}
+}
+
+func TestLookPath(t *testing.T) {
+	dir := t.TempDir()
+	bin := filepath.Join(dir, "bin")
+	os.Mkdir(bin, 0755)
+	os.WriteFile(filepath.Join(bin, "devserver"), []byte("#!/bin/sh\n"), 0755)
+	os.WriteFile(filepath.Join(bin, "notes"), []byte("not a program"), 0644)
+
+	env := []string{"HOME=/nowhere", "PATH=/does/not/exist:" + bin}
+
+	if got, err := lookPath("devserver", dir, env); err != nil || got != filepath.Join(bin, "devserver") {
+		t.Errorf("lookPath(devserver) = %q, %v", got, err)
+	}
+	if _, err := lookPath("notes", dir, env); err == nil {
+		t.Error("lookPath() found a file that isn't executable")
+	}
+	// Relative PATH entries and programs resolve against the process's directory
+	if got, err := lookPath("devserver", dir, []string{"PATH=bin"}); err != nil || got != filepath.Join(bin, "devserver") {
+		t.Errorf("lookPath() with a relative PATH = %q, %v", got, err)
+	}
+	if got, _ := lookPath("./bin/devserver", dir, env); got != filepath.Join(dir, "bin", "devserver") {
+		t.Errorf("lookPath(./bin/devserver) = %q", got)
+	}
+}
+
+func TestLaunchStart(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs sh")
+	}
+
+	sh, err := exec.LookPath("sh")
+	if err != nil {
+		t.Skip("needs sh")
+	}
+
+	dir := t.TempDir()
+	// The program comes from Exe, whatever Argv[0] says
+	launch := Launch{
+		Argv: []string{"devserver", "-c", `echo "$GREETING" > out.txt`},
+		Exe:  sh,
+		Dir:  dir,
+		Env:  []string{"GREETING=hello", "PATH=" + os.Getenv("PATH")},
+	}
+
+	pid, err := launch.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pid <= 0 {
+		t.Errorf("Start() = PID %d", pid)
+	}
+
+	// The process runs detached; wait for its output
+	out := filepath.Join(dir, "out.txt")
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(20 * time.Millisecond) {
+		if data, err := os.ReadFile(out); err == nil && strings.TrimSpace(string(data)) == "hello" {
+			return
+		}
+	}
+	t.Error("relaunched command did not run in its directory with its environment")
+}
+
+func TestLaunchStartWithoutEnvironment(t *testing.T) {
+	launch := Launch{Argv: []string{"sh", "-c", "true"}, Dir: t.TempDir()}
+	if _, err := launch.Start(); err == nil {
+		t.Error("Start() ran a command whose environment wasn't captured")
+	}
+}
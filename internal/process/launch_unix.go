@@ -0,0 +1,24 @@
+//go:build unix
+
+package process
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// detach starts the command in a new session, so it keeps running without
+// portman's terminal and isn't sent its hangup or Ctrl+C
+func detach(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+}
+
+// runAs starts the command as another user. Only root can switch, so
+// processes that already run as portman's user are started as they are.
+func runAs(cmd *exec.Cmd, c Credential) {
+	if int(c.UID) == os.Getuid() && int(c.GID) == os.Getgid() {
+		return
+	}
+	cmd.SysProcAttr.Credential = &syscall.Credential{Uid: c.UID, Gid: c.GID, Groups: c.Groups}
+}
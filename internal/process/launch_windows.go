@@ -0,0 +1,24 @@
+//go:build windows
+
+package process
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// Process creation flags from the Windows API
+const (
+	createNewProcessGroup = 0x00000200
+	detachedProcess       = 0x00000008
+)
+
+// detach starts the command without a console, in its own process group,
+// so it keeps running after portman exits and isn't sent its Ctrl+C
+func detach(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{CreationFlags: createNewProcessGroup | detachedProcess}
+}
+
+// runAs has nothing to do on Windows, where processes aren't captured with
+// their owner
+func runAs(cmd *exec.Cmd, c Credential) {}
@@ -0,0 +1,84 @@
+package process
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// PauseProcess suspends a process until ResumeProcess continues it. The
+// process keeps its ports open, but stops accepting and answering
+// connections. Protected processes are refused like KillProcess refuses them.
+func PauseProcess(pid int, opts KillOptions) KillResult {
+	if pid <= 0 {
+		return KillResult{Message: "Invalid PID"}
+	}
+
+	if reason, protected := CheckProtected(pid, opts.Port); protected && !opts.ForceProtected {
+		return KillResult{
+			Protected: true,
+			Message:   fmt.Sprintf("Refusing to pause PID %d: %s (use --force-protected to override)", pid, reason),
+		}
+	}
+
+	if err := suspend(pid); err != nil {
+		return KillResult{Message: fmt.Sprintf("Failed to pause process: %v", err)}
+	}
+	return KillResult{Success: true, Message: fmt.Sprintf("Process %d paused", pid)}
+}
+
+// ResumeProcess continues a process suspended by PauseProcess
+func ResumeProcess(pid int) KillResult {
+	if pid <= 0 {
+		return KillResult{Message: "Invalid PID"}
+	}
+
+	if err := resume(pid); err != nil {
+		return KillResult{Message: fmt.Sprintf("Failed to resume process: %v", err)}
+	}
+	return KillResult{Success: true, Message: fmt.Sprintf("Process %d resumed", pid)}
+}
+
+// Paused reports which of several processes are suspended. Suspended
+// processes can't be detected on Windows, so none are reported there.
+func Paused(ctx context.Context, pids []int) map[int]bool {
+	paused := make(map[int]bool)
+	switch runtime.GOOS {
+	case "linux":
+		for _, pid := range pids {
+			stat, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+			if err != nil {
+				continue
+			}
+			// "t" is stopped by a debugger, which portman didn't do
+			if fields, err := statFields(string(stat)); err == nil && fields[0] == "T" {
+				paused[pid] = true
+			}
+		}
+	case "darwin":
+		if len(pids) == 0 {
+			return paused
+		}
+		list := make([]string, len(pids))
+		for i, pid := range pids {
+			list[i] = strconv.Itoa(pid)
+		}
+		// ps exits non-zero if any PID is gone, but still reports the rest
+		output, _ := exec.CommandContext(ctx, "ps", "-o", "pid=,stat=", "-p", strings.Join(list, ",")).Output()
+		for _, line := range strings.Split(string(output), "\n") {
+			fields := strings.Fields(line)
+			if len(fields) != 2 || !strings.HasPrefix(fields[1], "T") {
+				continue
+			}
+			if pid, err := strconv.Atoi(fields[0]); err == nil {
+				paused[pid] = true
+			}
+		}
+	}
+	return paused
+}
@@ -0,0 +1,73 @@
+package process
+
+import (
+	"context"
+	"os/exec"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestPauseResume(t *testing.T) {
+	if runtime.GOOS != "linux" && runtime.GOOS != "darwin" {
+		t.Skip("paused processes are only detected on Linux and macOS")
+	}
+
+	cmd := exec.Command("sleep", "30")
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer cmd.Process.Kill()
+	pid := cmd.Process.Pid
+
+	// waitPaused polls, as the state changes asynchronously
+	waitPaused := func(want bool) {
+		t.Helper()
+		for deadline := time.Now().Add(2 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+			if Paused(context.Background(), []int{pid})[pid] == want {
+				return
+			}
+		}
+		t.Fatalf("Paused() = %v, want %v", !want, want)
+	}
+
+	if result := PauseProcess(pid, KillOptions{}); !result.Success {
+		t.Fatalf("PauseProcess() failed: %s", result.Message)
+	}
+	waitPaused(true)
+
+	if result := ResumeProcess(pid); !result.Success {
+		t.Fatalf("ResumeProcess() failed: %s", result.Message)
+	}
+	waitPaused(false)
+
+	if result := PauseProcess(0, KillOptions{}); result.Success {
+		t.Error("PauseProcess(0) succeeded")
+	}
+}
+
+func TestKillPausedProcess(t *testing.T) {
+	if runtime.GOOS != "linux" && runtime.GOOS != "darwin" {
+		t.Skip("paused processes are only detected on Linux and macOS")
+	}
+
+	// The shell exits on SIGTERM only once it is continued
+	cmd := exec.Command("sh", "-c", "trap 'exit 0' TERM; while :; do sleep 0.05; done")
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer cmd.Process.Kill()
+	go cmd.Wait()
+
+	// Let the shell set its trap before stopping it
+	time.Sleep(100 * time.Millisecond)
+	pid := cmd.Process.Pid
+	PauseProcess(pid, KillOptions{})
+	for deadline := time.Now().Add(2 * time.Second); !Paused(context.Background(), []int{pid})[pid] && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+	}
+	result := KillProcess(pid, KillOptions{Timeout: 5 * time.Second})
+	if !result.Success || result.Forced {
+		t.Errorf("KillProcess() of a paused process = %+v, want a graceful exit", result)
+	}
+}
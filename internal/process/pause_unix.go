@@ -0,0 +1,15 @@
+//go:build unix
+
+package process
+
+import "syscall"
+
+// suspend stops a process with SIGSTOP, which it can't catch or ignore
+func suspend(pid int) error {
+	return syscall.Kill(pid, syscall.SIGSTOP)
+}
+
+// resume continues a stopped process with SIGCONT
+func resume(pid int) error {
+	return syscall.Kill(pid, syscall.SIGCONT)
+}
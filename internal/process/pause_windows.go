@@ -0,0 +1,42 @@
+//go:build windows
+
+package process
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// processSuspendResume is the access right needed to suspend a process
+const processSuspendResume = 0x0800
+
+// Windows has no SIGSTOP; ntdll suspends every thread of a process at once
+var (
+	ntdll            = syscall.NewLazyDLL("ntdll.dll")
+	ntSuspendProcess = ntdll.NewProc("NtSuspendProcess")
+	ntResumeProcess  = ntdll.NewProc("NtResumeProcess")
+)
+
+// suspend suspends all threads of a process
+func suspend(pid int) error {
+	return callProcess(ntSuspendProcess, pid)
+}
+
+// resume resumes the threads of a suspended process
+func resume(pid int) error {
+	return callProcess(ntResumeProcess, pid)
+}
+
+// callProcess calls an ntdll function taking a process handle
+func callProcess(proc *syscall.LazyProc, pid int) error {
+	handle, err := syscall.OpenProcess(processSuspendResume, false, uint32(pid))
+	if err != nil {
+		return err
+	}
+	defer syscall.CloseHandle(handle)
+
+	if status, _, _ := proc.Call(uintptr(handle)); status != 0 {
+		return fmt.Errorf("%s failed with NTSTATUS 0x%08x", proc.Name, status)
+	}
+	return nil
+}
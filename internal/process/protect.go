@@ -0,0 +1,84 @@
+package process
+
+import (
+	"fmt"
+	"path/filepath"
+)
+
+// Policy lists processes that must not be killed without an explicit override
+type Policy struct {
+	Names []string // process names, e.g. "sshd"
+	Paths []string // executable paths, glob patterns allowed
+	Users []string // owning users
+	Ports []int    // ports whose owners are protected
+	PIDs  []int
+}
+
+// DefaultPolicy protects system services that a stray kill would break
+var DefaultPolicy = Policy{
+	Names: []string{"sshd", "systemd", "init", "launchd", "kernel_task", "WindowServer", "loginwindow"},
+	PIDs:  []int{1},
+}
+
+var policy = DefaultPolicy
+
+// SetPolicy replaces the protection policy enforced by KillProcess
+func SetPolicy(p Policy) {
+	policy = p
+}
+
+// CurrentPolicy returns the protection policy enforced by KillProcess
+func CurrentPolicy() Policy {
+	return policy
+}
+
+// Check reports whether a process is protected and why
+func (p Policy) Check(info Info, port int) (string, bool) {
+	for _, pid := range p.PIDs {
+		if info.PID == pid {
+			return fmt.Sprintf("PID %d is protected", pid), true
+		}
+	}
+
+	for _, name := range p.Names {
+		if info.Name == name || (info.Exe != "" && filepath.Base(info.Exe) == name) {
+			return fmt.Sprintf("process name %q is protected", name), true
+		}
+	}
+
+	if info.Exe != "" {
+		for _, pattern := range p.Paths {
+			if matched, _ := filepath.Match(pattern, info.Exe); matched || pattern == info.Exe {
+				return fmt.Sprintf("executable %s is protected", info.Exe), true
+			}
+		}
+	}
+
+	if info.User != "" {
+		for _, u := range p.Users {
+			if info.User == u {
+				return fmt.Sprintf("processes owned by %s are protected", u), true
+			}
+		}
+	}
+
+	if port > 0 {
+		for _, protected := range p.Ports {
+			if port == protected {
+				return fmt.Sprintf("port %d is protected", port), true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// CheckProtected reports whether the current policy protects a process
+func CheckProtected(pid int, port int) (string, bool) {
+	info, err := Lookup(pid)
+	if err != nil {
+		// Still honour PID and port rules for processes we can't inspect
+		info = Info{PID: pid}
+	}
+	return policy.Check(info, port)
+}
@@ -0,0 +1,43 @@
+package process
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// signalNames maps the signals portman can send to their conventional names
+var signalNames = map[syscall.Signal]string{
+	syscall.SIGHUP:  "SIGHUP",
+	syscall.SIGINT:  "SIGINT",
+	syscall.SIGQUIT: "SIGQUIT",
+	syscall.SIGKILL: "SIGKILL",
+	syscall.SIGTERM: "SIGTERM",
+}
+
+// ParseSignal parses a signal name ("TERM", "SIGTERM") or number ("15")
+func ParseSignal(s string) (syscall.Signal, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if n, err := strconv.Atoi(s); err == nil && n > 0 {
+		return syscall.Signal(n), nil
+	}
+
+	if !strings.HasPrefix(s, "SIG") {
+		s = "SIG" + s
+	}
+	for sig, name := range signalNames {
+		if name == s {
+			return sig, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown signal: %s", s)
+}
+
+// SignalName returns the conventional name of a signal
+func SignalName(sig syscall.Signal) string {
+	if name, ok := signalNames[sig]; ok {
+		return name
+	}
+	return fmt.Sprintf("signal %d", int(sig))
+}
@@ -0,0 +1,141 @@
+package process
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StartTimes returns when each of the given processes started. Processes
+// whose start time can't be read are left out.
+func StartTimes(ctx context.Context, pids []int) map[int]time.Time {
+	switch runtime.GOOS {
+	case "linux":
+		times := make(map[int]time.Time, len(pids))
+		for _, pid := range pids {
+			if t, err := startTimeProc(pid); err == nil {
+				times[pid] = t
+			}
+		}
+		return times
+	case "darwin":
+		return startTimesPS(ctx, pids)
+	default:
+		return map[int]time.Time{}
+	}
+}
+
+// startTimeProc reads a process's start time from /proc on Linux, where
+// field 22 of stat counts clock ticks since boot
+func startTimeProc(pid int) (time.Time, error) {
+	boot, err := bootTime()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	stat, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("process %d not found: %w", pid, err)
+	}
+	fields, err := statFields(string(stat))
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	ticks, err := strconv.ParseUint(fields[19], 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("malformed stat: %q", stat)
+	}
+	return boot.Add(time.Duration(ticks) * time.Second / clockTicks), nil
+}
+
+var (
+	bootOnce sync.Once
+	boot     time.Time
+	bootErr  error
+)
+
+// bootTime reads the system boot time from the btime line of /proc/stat
+func bootTime() (time.Time, error) {
+	bootOnce.Do(func() {
+		data, err := os.ReadFile("/proc/stat")
+		if err != nil {
+			bootErr = err
+			return
+		}
+		boot, bootErr = parseBootTime(string(data))
+	})
+	return boot, bootErr
+}
+
+// parseBootTime finds the btime line of /proc/stat
+func parseBootTime(data string) (time.Time, error) {
+	for _, line := range strings.Split(data, "\n") {
+		if seconds, ok := strings.CutPrefix(line, "btime "); ok {
+			n, err := strconv.ParseInt(strings.TrimSpace(seconds), 10, 64)
+			if err != nil {
+				return time.Time{}, fmt.Errorf("malformed btime: %q", line)
+			}
+			return time.Unix(n, 0), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("no btime in /proc/stat")
+}
+
+// startTimesPS reads start times from ps on macOS, in a single call
+func startTimesPS(ctx context.Context, pids []int) map[int]time.Time {
+	times := make(map[int]time.Time, len(pids))
+	if len(pids) == 0 {
+		return times
+	}
+
+	list := make([]string, len(pids))
+	for i, pid := range pids {
+		list[i] = strconv.Itoa(pid)
+	}
+
+	// ps exits non-zero if any PID is gone, but still reports the rest
+	output, _ := exec.CommandContext(ctx, "ps", "-o", "pid=,lstart=", "-p", strings.Join(list, ",")).Output()
+	for _, line := range strings.Split(string(output), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		pid, err := strconv.Atoi(fields[0])
+		if err != nil {
+			continue
+		}
+		if t, err := parseLstart(strings.Join(fields[1:], " ")); err == nil {
+			times[pid] = t
+		}
+	}
+	return times
+}
+
+// parseLstart parses the ps lstart format, e.g. "Mon Oct 5 09:14:02 2026"
+// once runs of spaces are collapsed
+func parseLstart(s string) (time.Time, error) {
+	return time.ParseInLocation("Mon Jan 2 15:04:05 2006", s, time.Local)
+}
+
+// FormatUptime formats how long a process has been running in its two
+// most significant units, e.g. "3d4h", "2h05m", "12m" or "45s"
+func FormatUptime(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+	default:
+		return fmt.Sprintf("%dd%dh", int(d.Hours())/24, int(d.Hours())%24)
+	}
+}
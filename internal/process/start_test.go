@@ -0,0 +1,40 @@
+package process
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseBootTime(t *testing.T) {
+	data := "cpu  10132153 290696 3084719 46828483 16683 0 25195 0 0 0\nintr 1462898 0 0\nctxt 1990473\nbtime 1760861234\nprocesses 2915\n"
+	got, err := parseBootTime(data)
+	if err != nil || !got.Equal(time.Unix(1760861234, 0)) {
+		t.Errorf("parseBootTime() = %v, %v; want %v", got, err, time.Unix(1760861234, 0))
+	}
+
+	if _, err := parseBootTime("cpu 1 2 3\n"); err == nil {
+		t.Error("parseBootTime() accepted /proc/stat without btime")
+	}
+}
+
+func TestParseLstart(t *testing.T) {
+	got, err := parseLstart("Mon Oct 5 09:14:02 2026")
+	want := time.Date(2026, time.October, 5, 9, 14, 2, 0, time.Local)
+	if err != nil || !got.Equal(want) {
+		t.Errorf("parseLstart() = %v, %v; want %v", got, err, want)
+	}
+}
+
+func TestFormatUptime(t *testing.T) {
+	tests := map[time.Duration]string{
+		45 * time.Second:                "45s",
+		12*time.Minute + 30*time.Second: "12m",
+		2*time.Hour + 5*time.Minute:     "2h05m",
+		76 * time.Hour:                  "3d4h",
+	}
+	for in, want := range tests {
+		if got := FormatUptime(in); got != want {
+			t.Errorf("FormatUptime(%v) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package process
+
+import "strings"
+
+// supervisors are the names, or command line fragments, of process
+// managers that restart their children when they exit
+var supervisors = []string{
+	"PM2", "pm2", "supervisord", "nodemon", "forever", "runsv", "s6-supervise",
+	"circusd", "systemd", "launchd", "air", "watchexec", "cargo-watch", "reflex",
+}
+
+// maxSupervisorDepth bounds the walk up the process tree
+const maxSupervisorDepth = 8
+
+// FindSupervisor walks up from pid's parent looking for a known process
+// manager, and ok is false when none is found. PID 1 is never returned: it
+// adopts orphans, and services it supervises are identified by their unit.
+func FindSupervisor(pid int) (Info, bool) {
+	info, err := Lookup(pid)
+	if err != nil || info.PPID <= 0 {
+		return Info{}, false
+	}
+
+	current, err := Lookup(info.PPID)
+	if err != nil {
+		return Info{}, false
+	}
+
+	for depth := 0; depth < maxSupervisorDepth && current.PID > 1; depth++ {
+		if isSupervisor(current) {
+			return current, true
+		}
+		if current.PPID <= 0 {
+			break
+		}
+		next, err := Lookup(current.PPID)
+		if err != nil {
+			break
+		}
+		current = next
+	}
+	return Info{}, false
+}
+
+// isSupervisor reports whether a process looks like a process manager.
+// Node and Python based managers are recognised by their command line.
+func isSupervisor(info Info) bool {
+	cmdline, _ := Cmdline(info.PID)
+	for _, name := range supervisors {
+		if info.Name == name || strings.HasPrefix(info.Name, name+" ") || containsWord(cmdline, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsWord reports whether any whitespace-separated field of s, or
+// its base name, equals word
+func containsWord(s, word string) bool {
+	for _, field := range strings.Fields(s) {
+		if field == word || strings.HasSuffix(field, "/"+word) || strings.HasPrefix(field, word+":") {
+			return true
+		}
+	}
+	return false
+}
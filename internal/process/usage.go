@@ -0,0 +1,227 @@
+package process
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// clockTicks is USER_HZ, the unit of CPU times in /proc/<pid>/stat. It is
+// 100 on every mainstream Linux architecture.
+const clockTicks = 100
+
+// Usage is a snapshot of a process's resource usage
+type Usage struct {
+	// RSS is the resident set size in bytes
+	RSS uint64 `json:"rss_bytes"`
+	// CPU is the CPU usage in percent of one core since the previous
+	// sample; it is only meaningful when CPUSampled is set
+	CPU        float64 `json:"cpu_percent"`
+	CPUSampled bool    `json:"-"`
+	Threads    int     `json:"threads,omitempty"`
+	// FDs is the number of open file descriptors, 0 when unknown
+	FDs int `json:"fds,omitempty"`
+
+	// cpuTime is the total user and system CPU time consumed so far
+	cpuTime time.Duration
+}
+
+// ReadUsage reads the current resource usage of a process. CPU is left
+// unsampled; use a Sampler to measure it between reads.
+func ReadUsage(ctx context.Context, pid int) (Usage, error) {
+	switch runtime.GOOS {
+	case "linux":
+		return readUsageProc(pid)
+	case "darwin":
+		return readUsagePS(ctx, pid)
+	default:
+		return Usage{}, fmt.Errorf("resource usage not supported on %s", runtime.GOOS)
+	}
+}
+
+// readUsageProc reads resource usage from /proc on Linux
+func readUsageProc(pid int) (Usage, error) {
+	dir := filepath.Join("/proc", strconv.Itoa(pid))
+	stat, err := os.ReadFile(filepath.Join(dir, "stat"))
+	if err != nil {
+		return Usage{}, fmt.Errorf("process %d not found: %w", pid, err)
+	}
+
+	usage, err := parseStat(string(stat))
+	if err != nil {
+		return Usage{}, err
+	}
+
+	// fd is unreadable for other users' processes without privileges
+	if fds, err := os.ReadDir(filepath.Join(dir, "fd")); err == nil {
+		usage.FDs = len(fds)
+	}
+	return usage, nil
+}
+
+// parseStat parses resource usage from /proc/<pid>/stat
+func parseStat(stat string) (Usage, error) {
+	fields, err := statFields(stat)
+	if err != nil {
+		return Usage{}, err
+	}
+
+	utime, _ := strconv.ParseUint(fields[11], 10, 64)
+	stime, _ := strconv.ParseUint(fields[12], 10, 64)
+	threads, _ := strconv.Atoi(fields[17])
+	rssPages, _ := strconv.ParseUint(fields[21], 10, 64)
+
+	return Usage{
+		RSS:     rssPages * uint64(os.Getpagesize()),
+		Threads: threads,
+		cpuTime: time.Duration(utime+stime) * time.Second / clockTicks,
+	}, nil
+}
+
+// statFields splits /proc/<pid>/stat into fields. The command name in
+// field 2 may contain spaces and parentheses, so fields are counted from
+// the last ")": fields[0] is field 3 (state) and field n is fields[n-3].
+func statFields(stat string) ([]string, error) {
+	i := strings.LastIndex(stat, ")")
+	if i < 0 {
+		return nil, fmt.Errorf("malformed stat: %q", stat)
+	}
+
+	fields := strings.Fields(stat[i+1:])
+	if len(fields) < 22 {
+		return nil, fmt.Errorf("malformed stat: %q", stat)
+	}
+	return fields, nil
+}
+
+// readUsagePS reads resource usage from ps on macOS
+func readUsagePS(ctx context.Context, pid int) (Usage, error) {
+	cmd := exec.CommandContext(ctx, "ps", "-o", "rss=,time=", "-p", strconv.Itoa(pid))
+	output, err := cmd.Output()
+	if err != nil {
+		return Usage{}, fmt.Errorf("process %d not found: %w", pid, err)
+	}
+
+	fields := strings.Fields(string(output))
+	if len(fields) < 2 {
+		return Usage{}, fmt.Errorf("process %d not found", pid)
+	}
+
+	// rss is reported in kilobytes
+	rss, _ := strconv.ParseUint(fields[0], 10, 64)
+	cpuTime, _ := parsePSTime(fields[1])
+	usage := Usage{RSS: rss * 1024, cpuTime: cpuTime}
+
+	// ps -M prints a header followed by one line per thread
+	if threads, err := exec.CommandContext(ctx, "ps", "-M", "-p", strconv.Itoa(pid)).Output(); err == nil {
+		usage.Threads = len(strings.Split(strings.TrimSpace(string(threads)), "\n")) - 1
+	}
+	return usage, nil
+}
+
+// parsePSTime parses the ps time format, [[dd-]hh:]mm:ss[.hh]
+func parsePSTime(s string) (time.Duration, error) {
+	var days int
+	if d, rest, ok := strings.Cut(s, "-"); ok {
+		n, err := strconv.Atoi(d)
+		if err != nil {
+			return 0, fmt.Errorf("invalid time %q", s)
+		}
+		days, s = n, rest
+	}
+
+	parts := strings.Split(s, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+
+	seconds, err := strconv.ParseFloat(parts[len(parts)-1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	total := time.Duration(seconds * float64(time.Second))
+
+	units := []time.Duration{time.Minute, time.Hour}
+	for i, unit := range units[:len(parts)-1] {
+		n, err := strconv.Atoi(parts[len(parts)-2-i])
+		if err != nil {
+			return 0, fmt.Errorf("invalid time %q", s)
+		}
+		total += time.Duration(n) * unit
+	}
+	return total + time.Duration(days)*24*time.Hour, nil
+}
+
+// Sampler measures CPU usage from the CPU time consumed between reads
+type Sampler struct {
+	mu   sync.Mutex
+	last map[int]cpuSample
+}
+
+// cpuSample is the CPU time a process had consumed at a point in time
+type cpuSample struct {
+	cpuTime time.Duration
+	at      time.Time
+}
+
+// NewSampler creates a sampler with no previous samples
+func NewSampler() *Sampler {
+	return &Sampler{last: make(map[int]cpuSample)}
+}
+
+// Sample reads a process's usage, with CPU measured since the previous
+// sample of the same PID
+func (s *Sampler) Sample(ctx context.Context, pid int) (Usage, error) {
+	usage, err := ReadUsage(ctx, pid)
+	if err != nil {
+		return Usage{}, err
+	}
+
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if prev, ok := s.last[pid]; ok && usage.cpuTime >= prev.cpuTime {
+		if elapsed := now.Sub(prev.at); elapsed > 0 {
+			usage.CPU = float64(usage.cpuTime-prev.cpuTime) / float64(elapsed) * 100
+			usage.CPUSampled = true
+		}
+	}
+	s.last[pid] = cpuSample{cpuTime: usage.cpuTime, at: now}
+	return usage, nil
+}
+
+// Forget drops samples of PIDs not in keep, so exited processes don't
+// accumulate and a reused PID doesn't inherit an old sample
+func (s *Sampler) Forget(keep map[int]bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for pid := range s.last {
+		if !keep[pid] {
+			delete(s.last, pid)
+		}
+	}
+}
+
+// FormatBytes formats a byte count with a binary unit, e.g. "12.5M"
+func FormatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	value := float64(n) / unit
+	for _, suffix := range []string{"K", "M", "G"} {
+		if value < unit {
+			return fmt.Sprintf("%.1f%s", value, suffix)
+		}
+		value /= unit
+	}
+	return fmt.Sprintf("%.1fT", value)
+}
@@ -0,0 +1,67 @@
+package process
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestParseStat(t *testing.T) {
+	// The command name contains a space and a parenthesis
+	stat := "4242 (node (dev) x) S 1 4242 4242 0 -1 4194560 9134 0 12 0 350 150 0 0 20 0 11 0 123456 1234567890 2560 18446744073709551615 1 1 0 0 0 0 0 4096 0 0 0 0 17 3 0 0 0 0 0\n"
+
+	usage, err := parseStat(stat)
+	if err != nil {
+		t.Fatalf("parseStat() error = %v", err)
+	}
+	if want := 5 * time.Second; usage.cpuTime != want {
+		t.Errorf("cpuTime = %v, want %v", usage.cpuTime, want)
+	}
+	if usage.Threads != 11 {
+		t.Errorf("Threads = %d, want 11", usage.Threads)
+	}
+	if want := uint64(2560 * os.Getpagesize()); usage.RSS != want {
+		t.Errorf("RSS = %d, want %d", usage.RSS, want)
+	}
+
+	if _, err := parseStat("4242 (node) S 1"); err == nil {
+		t.Error("parseStat() accepted a truncated stat line")
+	}
+}
+
+func TestParsePSTime(t *testing.T) {
+	tests := []struct {
+		in   string
+		want time.Duration
+	}{
+		{"0:01.50", 1500 * time.Millisecond},
+		{"12:34.00", 12*time.Minute + 34*time.Second},
+		{"01:02:03", time.Hour + 2*time.Minute + 3*time.Second},
+		{"2-00:00:01", 48*time.Hour + time.Second},
+	}
+
+	for _, tt := range tests {
+		got, err := parsePSTime(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("parsePSTime(%q) = %v, %v; want %v", tt.in, got, err, tt.want)
+		}
+	}
+
+	if _, err := parsePSTime("soon"); err == nil {
+		t.Error("parsePSTime() accepted an invalid time")
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := map[uint64]string{
+		512:             "512B",
+		2048:            "2.0K",
+		5 * 1024 * 1024: "5.0M",
+		3 << 30:         "3.0G",
+	}
+	for in, want := range tests {
+		if got := FormatBytes(in); got != want {
+			t.Errorf("FormatBytes(%d) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package project
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Checkout is the source tree a process is running from, detected from
+// the package manifest or git repository enclosing its working directory
+type Checkout struct {
+	// Name is the package name, or the directory name when there is none
+	Name string `json:"name"`
+	// Root is the directory holding the manifest or .git
+	Root string `json:"root"`
+	// Repo is the name of the enclosing git repository's directory, which
+	// tells apart several checkouts of the same package
+	Repo string `json:"repo,omitempty"`
+}
+
+// String formats the checkout as "name", or "name (repo)" when the git
+// repository is named differently from the package
+func (c Checkout) String() string {
+	if c.Repo == "" || c.Repo == c.Name {
+		return c.Name
+	}
+	return c.Name + " (" + c.Repo + ")"
+}
+
+// Matches reports whether filter, compared case-insensitively, is part of
+// the checkout's name, repository or root
+func (c Checkout) Matches(filter string) bool {
+	filter = strings.ToLower(filter)
+	return strings.Contains(strings.ToLower(c.Name), filter) ||
+		strings.Contains(strings.ToLower(c.Repo), filter) ||
+		strings.Contains(strings.ToLower(c.Root), filter)
+}
+
+// manifests are the package files checked in each directory, in order,
+// with the function that reads the package name from each
+var manifests = []struct {
+	file string
+	name func(path string) string
+}{
+	{"package.json", packageJSONName},
+	{"go.mod", goModName},
+	{"Cargo.toml", tomlName("[package]")},
+	{"pyproject.toml", tomlName("[project]", "[tool.poetry]")},
+}
+
+// Detect finds the checkout enclosing dir: the nearest directory with a
+// package manifest, named after its package, within the nearest git
+// repository. The home directory and the filesystem root are never
+// treated as a checkout.
+func Detect(dir string) (Checkout, bool) {
+	if dir == "" || !filepath.IsAbs(dir) {
+		return Checkout{}, false
+	}
+	home, _ := os.UserHomeDir()
+
+	var checkout Checkout
+	for dir = filepath.Clean(dir); ; dir = filepath.Dir(dir) {
+		if dir == home || dir == filepath.Dir(dir) {
+			break
+		}
+
+		if checkout.Root == "" {
+			for _, m := range manifests {
+				if name := m.name(filepath.Join(dir, m.file)); name != "" {
+					checkout = Checkout{Name: name, Root: dir}
+					break
+				}
+			}
+		}
+
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			checkout.Repo = filepath.Base(dir)
+			if checkout.Root == "" {
+				checkout = Checkout{Name: checkout.Repo, Root: dir, Repo: checkout.Repo}
+			}
+			break
+		}
+	}
+
+	return checkout, checkout.Root != ""
+}
+
+// packageJSONName reads the name of an npm package, falling back to the
+// directory name for unnamed packages
+func packageJSONName(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+
+	var pkg struct {
+		Name string `json:"name"`
+	}
+	if json.Unmarshal(data, &pkg) != nil || pkg.Name == "" {
+		return filepath.Base(filepath.Dir(path))
+	}
+	return pkg.Name
+}
+
+// goModName reads the last element of a Go module path
+func goModName(path string) string {
+	f, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		if module, ok := strings.CutPrefix(strings.TrimSpace(s.Text()), "module "); ok {
+			module = strings.Trim(strings.TrimSpace(module), `"`)
+			return module[strings.LastIndex(module, "/")+1:]
+		}
+	}
+	return filepath.Base(filepath.Dir(path))
+}
+
+// tomlName returns a reader for the name key of the first of the given
+// TOML tables, falling back to the directory name when there is none
+func tomlName(tables ...string) func(path string) string {
+	return func(path string) string {
+		f, err := os.Open(path)
+		if err != nil {
+			return ""
+		}
+		defer f.Close()
+
+		inTable := false
+		s := bufio.NewScanner(f)
+		for s.Scan() {
+			line := strings.TrimSpace(s.Text())
+			if strings.HasPrefix(line, "[") {
+				inTable = false
+				for _, t := range tables {
+					inTable = inTable || line == t
+				}
+				continue
+			}
+			if !inTable {
+				continue
+			}
+			if key, value, ok := strings.Cut(line, "="); ok && strings.TrimSpace(key) == "name" {
+				if name := strings.Trim(strings.TrimSpace(value), `"'`); name != "" {
+					return name
+				}
+			}
+		}
+		return filepath.Base(filepath.Dir(path))
+	}
+}
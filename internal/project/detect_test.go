@@ -0,0 +1,63 @@
+package project
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeFile creates a file and its parent directories under root
+func writeFile(t *testing.T, root, path, content string) {
+	t.Helper()
+	path = filepath.Join(root, path)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDetect(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, root, "shop-feature/.git/HEAD", "ref: refs/heads/main\n")
+	writeFile(t, root, "shop-feature/apps/web/package.json", `{"name": "@shop/web"}`)
+	writeFile(t, root, "shop-feature/apps/web/src/index.js", "")
+	writeFile(t, root, "shop-feature/services/api/go.mod", "module github.com/acme/shop/api\n\ngo 1.25\n")
+	writeFile(t, root, "shop-feature/tools/seed.sh", "")
+	writeFile(t, root, "scratch/pyproject.toml", "[build-system]\nrequires = []\n\n[project]\nname = \"scratchpad\"\n")
+	writeFile(t, root, "crates/cli/Cargo.toml", "[workspace]\n\n[package]\nname = 'shop-cli'\n")
+	writeFile(t, root, "loose/notes.txt", "")
+
+	repo := filepath.Join(root, "shop-feature")
+	tests := []struct {
+		dir  string
+		want Checkout
+		ok   bool
+	}{
+		{"shop-feature/apps/web/src", Checkout{Name: "@shop/web", Root: filepath.Join(repo, "apps/web"), Repo: "shop-feature"}, true},
+		{"shop-feature/services/api", Checkout{Name: "api", Root: filepath.Join(repo, "services/api"), Repo: "shop-feature"}, true},
+		{"shop-feature/tools", Checkout{Name: "shop-feature", Root: repo, Repo: "shop-feature"}, true},
+		{"scratch", Checkout{Name: "scratchpad", Root: filepath.Join(root, "scratch")}, true},
+		{"crates/cli", Checkout{Name: "shop-cli", Root: filepath.Join(root, "crates/cli")}, true},
+		{"loose", Checkout{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.dir, func(t *testing.T) {
+			got, ok := Detect(filepath.Join(root, tt.dir))
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("Detect() = %+v, %v; want %+v, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestCheckoutString(t *testing.T) {
+	if got := (Checkout{Name: "web", Repo: "web"}).String(); got != "web" {
+		t.Errorf("String() = %q, want %q", got, "web")
+	}
+	if got := (Checkout{Name: "@shop/web", Repo: "shop-feature"}).String(); got != "@shop/web (shop-feature)" {
+		t.Errorf("String() = %q, want %q", got, "@shop/web (shop-feature)")
+	}
+}
@@ -0,0 +1,202 @@
+package project
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/NoaTamburrini/portman/internal/health"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FileName is the project file portman looks for
+const FileName = ".portman"
+
+// ErrNoProject is returned by commands that need a project file
+var ErrNoProject = errors.New("no " + FileName + " file found in this directory or any parent")
+
+// Service is a named port declared in a project file, either as
+// "api: 8080" or as a mapping with the optional fields below
+type Service struct {
+	Name string
+	Port int
+	// Protocol is "tcp", the default, or "udp"
+	Protocol string
+	// Command is a substring expected in the owning process's command line
+	Command string
+	// Required services make `portman status` exit non-zero when down
+	Required bool
+	// Probe is "tcp", "http", an HTTP path like "/health", or a full URL
+	Probe string
+	// Timeout bounds the health probe
+	Timeout time.Duration
+}
+
+// serviceSpec is the mapping form of a service in the project file
+type serviceSpec struct {
+	Port     int    `yaml:"port"`
+	Protocol string `yaml:"protocol"`
+	Command  string `yaml:"command"`
+	Required *bool  `yaml:"required"`
+	Probe    string `yaml:"probe"`
+	Timeout  string `yaml:"timeout"`
+}
+
+// Project is a parsed project file
+type Project struct {
+	// Root is the directory containing the project file
+	Root     string
+	Path     string
+	Services []Service
+}
+
+// Find walks up from dir looking for a project file. It returns nil
+// without an error when there is none.
+func Find(dir string) (*Project, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		path := filepath.Join(dir, FileName)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return Load(path)
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return nil, nil
+		}
+		dir = parent
+	}
+}
+
+// Load parses the project file at path
+func Load(path string) (*Project, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read project file: %w", err)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid project file %s: %w", path, err)
+	}
+
+	p := &Project{
+		Root: filepath.Dir(path),
+		Path: path,
+	}
+
+	// An empty file declares no services
+	if len(doc.Content) == 0 {
+		return p, nil
+	}
+
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("invalid project file %s: expected a mapping of service names to ports", path)
+	}
+
+	// Walk the mapping by hand to keep services in file order
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key := root.Content[i]
+		if _, dup := p.Lookup(key.Value); dup {
+			return nil, fmt.Errorf("invalid project file %s: line %d: service %q is declared twice", path, key.Line, key.Value)
+		}
+		service, err := parseService(key.Value, root.Content[i+1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid project file %s: %w", path, err)
+		}
+		p.Services = append(p.Services, service)
+	}
+
+	return p, nil
+}
+
+// parseService parses a service given as a bare port or a mapping
+func parseService(name string, node *yaml.Node) (Service, error) {
+	service := Service{Name: name, Protocol: "tcp", Required: true}
+
+	if node.Kind == yaml.MappingNode {
+		var spec serviceSpec
+		if err := node.Decode(&spec); err != nil {
+			return service, fmt.Errorf("line %d: service %q: %w", node.Line, name, err)
+		}
+		service.Port = spec.Port
+		service.Command = spec.Command
+		switch spec.Protocol {
+		case "", "tcp":
+		case "udp":
+			service.Protocol = "udp"
+		default:
+			return service, fmt.Errorf("line %d: protocol for %q must be tcp or udp, not %q", node.Line, name, spec.Protocol)
+		}
+		if spec.Probe != "" {
+			if service.Protocol != "tcp" {
+				return service, fmt.Errorf("line %d: probes need a TCP service, but %q is %s", node.Line, name, service.Protocol)
+			}
+			if err := health.ValidateSpec(spec.Probe); err != nil {
+				return service, fmt.Errorf("line %d: %v for %q", node.Line, err, name)
+			}
+		}
+		service.Probe = spec.Probe
+		if spec.Required != nil {
+			service.Required = *spec.Required
+		}
+		if spec.Timeout != "" {
+			timeout, err := time.ParseDuration(spec.Timeout)
+			if err != nil {
+				return service, fmt.Errorf("line %d: invalid timeout %q for %q", node.Line, spec.Timeout, name)
+			}
+			service.Timeout = timeout
+		}
+	} else if err := node.Decode(&service.Port); err != nil {
+		return service, fmt.Errorf("line %d: port for %q must be a number", node.Line, name)
+	}
+
+	if service.Port < 1 || service.Port > 65535 {
+		return service, fmt.Errorf("line %d: port %d for %q out of range", node.Line, service.Port, name)
+	}
+	return service, nil
+}
+
+// Lookup finds a service by name
+func (p *Project) Lookup(name string) (Service, bool) {
+	if p == nil {
+		return Service{}, false
+	}
+	for _, s := range p.Services {
+		if s.Name == name {
+			return s, true
+		}
+	}
+	return Service{}, false
+}
+
+// ServiceForPort finds the service declared on a port
+func (p *Project) ServiceForPort(port int) (Service, bool) {
+	if p == nil {
+		return Service{}, false
+	}
+	for _, s := range p.Services {
+		if s.Port == port {
+			return s, true
+		}
+	}
+	return Service{}, false
+}
+
+// Contains reports whether path lies inside the project root
+func (p *Project) Contains(path string) bool {
+	rel, err := filepath.Rel(p.Root, path)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
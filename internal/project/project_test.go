@@ -0,0 +1,163 @@
+package project
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []Service
+		err     string
+	}{
+		{"empty", "", nil, ""},
+		{"comments only", "# no services yet\n", nil, ""},
+		{
+			"bare ports in file order",
+			"web: 3000\napi: 8080\ndb: 5432\n",
+			[]Service{
+				{Name: "web", Port: 3000, Protocol: "tcp", Required: true},
+				{Name: "api", Port: 8080, Protocol: "tcp", Required: true},
+				{Name: "db", Port: 5432, Protocol: "tcp", Required: true},
+			},
+			"",
+		},
+		{
+			"mapping",
+			"api:\n  port: 8080\n  command: uvicorn\n  required: false\n  probe: /health\n  timeout: 500ms\n",
+			[]Service{{Name: "api", Port: 8080, Protocol: "tcp", Command: "uvicorn", Probe: "/health", Timeout: 500 * time.Millisecond}},
+			"",
+		},
+		{
+			"udp",
+			"dns:\n  port: 5353\n  protocol: udp\n",
+			[]Service{{Name: "dns", Port: 5353, Protocol: "udp", Required: true}},
+			"",
+		},
+		{"unknown protocol", "dns:\n  port: 5353\n  protocol: sctp\n", nil, `protocol for "dns" must be tcp or udp`},
+		{"probe of a udp service", "dns:\n  port: 5353\n  protocol: udp\n  probe: tcp\n", nil, `probes need a TCP service`},
+		{"not a mapping", "- web\n- api\n", nil, "expected a mapping"},
+		{"malformed yaml", "web: [3000\n", nil, "invalid project file"},
+		{"port not a number", "web: three thousand\n", nil, `line 1: port for "web" must be a number`},
+		{"port out of range", "web: 3000\napi: 70000\n", nil, `line 2: port 70000 for "api" out of range`},
+		{"port zero", "web: 0\n", nil, "out of range"},
+		{"missing port", "api:\n  command: uvicorn\n", nil, `port 0 for "api" out of range`},
+		{"invalid timeout", "api:\n  port: 8080\n  timeout: soon\n", nil, `invalid timeout "soon"`},
+		{"unknown probe", "api:\n  port: 8080\n  probe: grpc\n", nil, `line 2: unknown probe "grpc"`},
+		{"probe URL without a host", "api:\n  port: 8080\n  probe: http://\n", nil, `invalid probe URL`},
+		{"unknown field type", "api:\n  port: [8080]\n", nil, `service "api"`},
+		{"duplicate name", "web: 3000\napi: 8080\nweb: 3001\n", nil, `line 3: service "web" is declared twice`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			writeFile(t, root, FileName, tt.content)
+
+			p, err := Load(filepath.Join(root, FileName))
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("Load() error = %v, want one containing %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if p.Root != root || !reflect.DeepEqual(p.Services, tt.want) {
+				t.Errorf("Load() = %+v, want services %+v in %s", p, tt.want, root)
+			}
+		})
+	}
+}
+
+func TestFind(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, root, "shop/"+FileName, "web: 3000\n")
+	writeFile(t, root, "shop/apps/web/src/index.js", "")
+	writeFile(t, root, "shop/apps/web/"+FileName+"/README", "")
+	writeFile(t, root, "elsewhere/notes.txt", "")
+
+	tests := []struct {
+		dir  string
+		want string // project root, relative to root; "" for none
+	}{
+		{"shop", "shop"},
+		{"shop/apps/web/src", "shop"},
+		// A directory named .portman is not a project file
+		{"shop/apps/web", "shop"},
+		{"elsewhere", ""},
+	}
+
+	for _, tt := range tests {
+		p, err := Find(filepath.Join(root, tt.dir))
+		if err != nil {
+			t.Fatalf("Find(%s): %v", tt.dir, err)
+		}
+		if tt.want == "" {
+			if p != nil {
+				t.Errorf("Find(%s) = %s, want none", tt.dir, p.Path)
+			}
+			continue
+		}
+		if p == nil || p.Root != filepath.Join(root, tt.want) {
+			t.Errorf("Find(%s) = %+v, want the project in %s", tt.dir, p, tt.want)
+		}
+	}
+
+	// A broken project file is reported rather than skipped
+	writeFile(t, root, "broken/"+FileName, "web: nope\n")
+	writeFile(t, root, "broken/src/main.go", "")
+	if _, err := Find(filepath.Join(root, "broken/src")); err == nil {
+		t.Error("Find() accepted a broken project file")
+	}
+}
+
+func TestLookup(t *testing.T) {
+	p := &Project{
+		Root: "/src/shop",
+		Services: []Service{
+			{Name: "web", Port: 3000},
+			{Name: "api", Port: 8080},
+		},
+	}
+
+	if s, ok := p.Lookup("api"); !ok || s.Port != 8080 {
+		t.Errorf("Lookup(api) = %+v, %v", s, ok)
+	}
+	if _, ok := p.Lookup("API"); ok {
+		t.Error("Lookup() is case-insensitive")
+	}
+	if s, ok := p.ServiceForPort(3000); !ok || s.Name != "web" {
+		t.Errorf("ServiceForPort(3000) = %+v, %v", s, ok)
+	}
+	if _, ok := p.ServiceForPort(5432); ok {
+		t.Error("ServiceForPort(5432) found an undeclared port")
+	}
+
+	// Commands call these without checking for a project
+	var none *Project
+	if _, ok := none.Lookup("web"); ok {
+		t.Error("nil Lookup() found a service")
+	}
+	if _, ok := none.ServiceForPort(3000); ok {
+		t.Error("nil ServiceForPort() found a service")
+	}
+
+	for path, want := range map[string]bool{
+		"/src/shop":          true,
+		"/src/shop/apps/web": true,
+		"/src/shopping":      false,
+		"/src":               false,
+		"/tmp":               false,
+	} {
+		if got := p.Contains(path); got != want {
+			t.Errorf("Contains(%s) = %v, want %v", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+// benchmarkSizes are the socket counts of the synthetic fixtures, up to a
+// build host with thousands of open sockets
+var benchmarkSizes = []int{100, 1000, 10000}
+
+// Each synthetic fixture has one listener per ten sockets, the rest being
+// connections accepted on them, and a UDP socket per hundred
+
+// syntheticLsof generates `lsof -i -P -n` output listing n sockets
+func syntheticLsof(n int) string {
+	var b strings.Builder
+	b.WriteString("COMMAND     PID   USER   FD   TYPE DEVICE SIZE/OFF NODE NAME\n")
+	for i := 0; i < n; i++ {
+		pid, port := 1000+i/10, 20000+i/10
+		switch {
+		case i%100 == 99:
+			fmt.Fprintf(&b, "dnsd     %5d   noa   %3du  IPv4 %6d      0t0  UDP *:%d\n", pid, i%1000, 40000+i, port+10000)
+		case i%10 == 0:
+			fmt.Fprintf(&b, "node     %5d   noa   %3du  IPv6 %6d      0t0  TCP *:%d (LISTEN)\n", pid, i%1000, 40000+i, port)
+		default:
+			fmt.Fprintf(&b, "node     %5d   noa   %3du  IPv6 %6d      0t0  TCP 127.0.0.1:%d->127.0.0.1:%d (ESTABLISHED)\n", pid, i%1000, 40000+i, port, 50000+i%10000)
+		}
+	}
+	return b.String()
+}
+
+// syntheticSS generates `ss -tuanp` output listing n sockets
+func syntheticSS(n int) string {
+	var b strings.Builder
+	b.WriteString("Netid State     Recv-Q Send-Q      Local Address:Port        Peer Address:Port  Process\n")
+	for i := 0; i < n; i++ {
+		pid, port := 1000+i/10, 20000+i/10
+		switch {
+		case i%100 == 99:
+			fmt.Fprintf(&b, "udp   UNCONN    0      0                 0.0.0.0:%d            0.0.0.0:*      users:((\"dnsd\",pid=%d,fd=%d))\n", port+10000, pid, i%1000)
+		case i%10 == 0:
+			fmt.Fprintf(&b, "tcp   LISTEN    0      4096              0.0.0.0:%d            0.0.0.0:*      users:((\"node\",pid=%d,fd=%d))\n", port, pid, i%1000)
+		default:
+			fmt.Fprintf(&b, "tcp   ESTAB     0      0               127.0.0.1:%d          127.0.0.1:%d  users:((\"node\",pid=%d,fd=%d))\n", port, 50000+i%10000, pid, i%1000)
+		}
+	}
+	return b.String()
+}
+
+// syntheticNetstat generates `netstat -ano` output listing n sockets
+func syntheticNetstat(n int) string {
+	var b strings.Builder
+	b.WriteString("\nActive Connections\n\n  Proto  Local Address          Foreign Address        State           PID\n")
+	for i := 0; i < n; i++ {
+		pid, port := 1000+i/10, 20000+i/10
+		switch {
+		case i%100 == 99:
+			fmt.Fprintf(&b, "  UDP    0.0.0.0:%d            *:*                                    %d\n", port+10000, pid)
+		case i%10 == 0:
+			fmt.Fprintf(&b, "  TCP    0.0.0.0:%d            0.0.0.0:0              LISTENING       %d\n", port, pid)
+		default:
+			fmt.Fprintf(&b, "  TCP    127.0.0.1:%d          127.0.0.1:%d        ESTABLISHED     %d\n", port, 50000+i%10000, pid)
+		}
+	}
+	return b.String()
+}
+
+// syntheticProcNet generates a /proc/net/tcp table listing n sockets
+func syntheticProcNet(n int) string {
+	var b strings.Builder
+	b.WriteString("  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode\n")
+	for i := 0; i < n; i++ {
+		port := 20000 + i/10
+		if i%10 == 0 {
+			fmt.Fprintf(&b, "%4d: 00000000:%04X 00000000:0000 0A 00000000:00000000 00:00000000 00000000  1000        0 %d 1 0000000000000000 100 0 0 10 0\n", i, port, 40000+i)
+		} else {
+			fmt.Fprintf(&b, "%4d: 0100007F:%04X 0100007F:%04X 01 00000000:00000000 00:00000000 00000000  1000        0 %d 1 0000000000000000 20 4 30 10 -1\n", i, port, 50000+i%10000, 40000+i)
+		}
+	}
+	return b.String()
+}
+
+func BenchmarkParsers(b *testing.B) {
+	parsers := []struct {
+		name     string
+		generate func(int) string
+		parse    func(string)
+	}{
+		{"lsof", syntheticLsof, func(s string) { parseUnixOutput(s) }},
+		{"lsof-sockets", syntheticLsof, func(s string) { parseLsofSockets(s) }},
+		{"ss", syntheticSS, func(s string) { parseSSOutput(s) }},
+		{"netstat", syntheticNetstat, func(s string) { parseWindowsOutput(s) }},
+		{"netstat-sockets", syntheticNetstat, func(s string) { parseNetstatSockets(s) }},
+		{"procnet", syntheticProcNet, func(s string) { parseProcNet(s, "tcp") }},
+		{"procnet-sockets", syntheticProcNet, func(s string) { procTCPSockets(s) }},
+	}
+
+	for _, p := range parsers {
+		for _, n := range benchmarkSizes {
+			output := p.generate(n)
+			b.Run(fmt.Sprintf("%s/%d", p.name, n), func(b *testing.B) {
+				b.SetBytes(int64(len(output)))
+				for i := 0; i < b.N; i++ {
+					p.parse(output)
+				}
+			})
+		}
+	}
+}
+
+// BenchmarkBackends scans this machine with each backend that is
+// installed, reporting the time spent in each stage
+func BenchmarkBackends(b *testing.B) {
+	backends := []struct {
+		name string
+		os   string
+		scan func(context.Context, *Timing) ([]Port, []tcpSocket, error)
+	}{
+		{"lsof", "", scanPortsUnix},
+		{"ss", "linux", scanPortsSS},
+		{"netstat", "windows", scanPortsWindows},
+	}
+
+	for _, backend := range backends {
+		b.Run(backend.name, func(b *testing.B) {
+			if _, err := exec.LookPath(backend.name); err != nil || (backend.os != "" && backend.os != runtime.GOOS) {
+				b.Skipf("%s is not available", backend.name)
+			}
+
+			// The timing accumulates over every iteration
+			var t Timing
+			for i := 0; i < b.N; i++ {
+				ports, sockets, err := backend.scan(context.Background(), &t)
+				if err != nil {
+					b.Fatal(err)
+				}
+				start := time.Now()
+				enrich(context.Background(), listening(ports), sockets)
+				t.Enrich += time.Since(start)
+			}
+			perOp := func(d time.Duration) float64 { return float64(d.Nanoseconds()) / float64(b.N) }
+			b.ReportMetric(perOp(t.Exec), "exec-ns/op")
+			b.ReportMetric(perOp(t.Parse), "parse-ns/op")
+			b.ReportMetric(perOp(t.Enrich), "enrich-ns/op")
+		})
+	}
+}
+
+// TestSyntheticFixtures checks the benchmarks parse what they should
+func TestSyntheticFixtures(t *testing.T) {
+	const n = 1000
+	lsof, _ := parseUnixOutput(syntheticLsof(n))
+	netstat, _ := parseWindowsOutput(syntheticNetstat(n))
+
+	tests := []struct {
+		name string
+		got  int
+		want int
+	}{
+		// A hundred TCP listeners and ten UDP sockets
+		{"lsof", len(listening(lsof)), 110},
+		{"ss", len(listening(parseSSOutput(syntheticSS(n)))), 110},
+		{"netstat", len(listening(netstat)), 110},
+		// Everything but the listeners
+		{"procnet", len(procTCPSockets(syntheticProcNet(n))), 900},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s fixture: parsed %d sockets, want %d", tt.name, tt.got, tt.want)
+		}
+	}
+}
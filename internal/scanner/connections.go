@@ -0,0 +1,347 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/NoaTamburrini/portman/internal/process"
+)
+
+// Connection is a client connection accepted on a listening TCP port
+type Connection struct {
+	// Local is the listener's end and Remote the client's, as "ip:port"
+	Local  string `json:"local"`
+	Remote string `json:"remote"`
+	State  string `json:"state"`
+	// ClientPID and ClientName identify the client when it runs on this
+	// host and its socket can be seen
+	ClientPID  int    `json:"client_pid,omitempty"`
+	ClientName string `json:"client_process,omitempty"`
+	// SendQueue is the bytes sent but not yet acknowledged by the client,
+	// RecvQueue the bytes received but not yet read by the server. Only
+	// known on Linux.
+	SendQueue int `json:"send_queue"`
+	RecvQueue int `json:"recv_queue"`
+}
+
+// tcpSocket is a TCP socket with a peer, as listed by the OS
+type tcpSocket struct {
+	local, remote string
+	localPort     int
+	state         string
+	// pid and name are the owner, when the listing names it
+	pid  int
+	name string
+	// inode identifies the socket on Linux, where the owner is found
+	// through /proc/<pid>/fd
+	inode            uint64
+	txQueue, rxQueue int
+}
+
+// QueuesKnown reports whether connection queues can be read on this OS
+func QueuesKnown() bool {
+	return runtime.GOOS == "linux"
+}
+
+// CountEstablished counts the established connections on a TCP port,
+// whether or not it is still listening, without the cost of a full scan
+func CountEstablished(ctx context.Context, p Port) (int, error) {
+	sockets, err := tcpSockets(ctx, p.Namespace, p.PID)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, s := range sockets {
+		if s.localPort == p.Number && s.state == StateEstablished {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// enrichConnections attaches the connections accepted on each listening
+// TCP port. Connections arrive on the listener's port number, within its
+// network namespace. Outside Linux they are the sockets the backend
+// listed; on Linux each namespace's /proc table is read for them.
+func enrichConnections(ctx context.Context, ports []Port, sockets []tcpSocket) {
+	if runtime.GOOS != "linux" {
+		attachConnections(ctx, ports, "", sockets)
+		return
+	}
+
+	pids := make(map[string]int) // a process in each namespace
+	for _, p := range ports {
+		if p.Protocol != "tcp" || p.State != StateListen {
+			continue
+		}
+		if _, ok := pids[p.Namespace]; !ok {
+			pids[p.Namespace] = p.PID
+		}
+	}
+
+	for namespace, pid := range pids {
+		sockets, err := tcpSockets(ctx, namespace, pid)
+		if err != nil {
+			continue
+		}
+		attachConnections(ctx, ports, namespace, sockets)
+	}
+}
+
+// tcpSockets lists the TCP sockets with a peer in a network namespace,
+// identified by one of its processes. Outside Linux it runs the backend
+// again, so scans use the sockets they already listed instead.
+func tcpSockets(ctx context.Context, namespace string, pid int) ([]tcpSocket, error) {
+	switch runtime.GOOS {
+	case "linux":
+		dir := "/proc/net"
+		if namespace != "" {
+			dir = fmt.Sprintf("/proc/%d/net", pid)
+		}
+		var sockets []tcpSocket
+		for _, table := range []string{"tcp", "tcp6"} {
+			content, err := os.ReadFile(dir + "/" + table)
+			if err != nil {
+				continue
+			}
+			sockets = append(sockets, procTCPSockets(string(content))...)
+		}
+		return sockets, nil
+	case "darwin":
+		// lsof exits non-zero when nothing matches
+		output, _ := exec.CommandContext(ctx, "lsof", "-iTCP", "-sTCP:^LISTEN", "-P", "-n").Output()
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return parseLsofSockets(string(output)), nil
+	case "windows":
+		output, err := exec.CommandContext(ctx, "netstat", "-ano").Output()
+		if err != nil {
+			return nil, fmt.Errorf("failed to execute netstat: %w", err)
+		}
+		return parseNetstatSockets(string(output)), nil
+	default:
+		return nil, fmt.Errorf("unsupported operating system: %s", runtime.GOOS)
+	}
+}
+
+// procTCPSockets lists the sockets with a peer in a /proc/net/tcp table
+func procTCPSockets(content string) []tcpSocket {
+	var sockets []tcpSocket
+	for _, s := range parseProcNet(content, "tcp") {
+		// TIME_WAIT sockets belong to no process and have no client left
+		if s.State == tcpListen || s.State == tcpTimeWait || s.RemotePort == 0 {
+			continue
+		}
+		state, ok := procTCPStates[s.State]
+		if !ok {
+			state = s.State
+		}
+		sockets = append(sockets, tcpSocket{
+			local:     endpoint(s.LocalIP, s.LocalPort),
+			remote:    endpoint(s.RemoteIP, s.RemotePort),
+			localPort: s.LocalPort,
+			state:     state,
+			inode:     s.Inode,
+			txQueue:   s.TxQueue,
+			rxQueue:   s.RxQueue,
+		})
+	}
+	return sockets
+}
+
+// parseLsofSockets lists the TCP connections in `lsof -i -P -n` output
+func parseLsofSockets(output string) []tcpSocket {
+	var sockets []tcpSocket
+	for _, line := range strings.Split(output, "\n") {
+		row, ok := parseLsofRow(line)
+		if !ok || row.protocol != "tcp" || row.state == StateListen || row.state == "TIME_WAIT" {
+			continue
+		}
+		localAddr, remoteAddr, ok := strings.Cut(row.address, "->")
+		if !ok {
+			continue
+		}
+		local, localPort, ok := parseEndpoint(localAddr)
+		if !ok {
+			continue
+		}
+		remote, _, ok := parseEndpoint(remoteAddr)
+		if !ok {
+			continue
+		}
+		sockets = append(sockets, tcpSocket{
+			local:     local,
+			remote:    remote,
+			localPort: localPort,
+			state:     row.state,
+			pid:       row.pid,
+			name:      row.name,
+		})
+	}
+	return sockets
+}
+
+// parseNetstatSockets lists the TCP connections in `netstat -ano` output
+func parseNetstatSockets(output string) []tcpSocket {
+	var sockets []tcpSocket
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 5 || !strings.EqualFold(fields[0], "tcp") {
+			continue
+		}
+		state := fields[3]
+		if state == "LISTENING" || state == "TIME_WAIT" {
+			continue
+		}
+		pid, err := strconv.Atoi(fields[4])
+		if err != nil || pid <= 0 {
+			continue
+		}
+		local, localPort, ok := parseEndpoint(fields[1])
+		if !ok {
+			continue
+		}
+		remote, _, ok := parseEndpoint(fields[2])
+		if !ok {
+			continue
+		}
+		sockets = append(sockets, tcpSocket{
+			local:     local,
+			remote:    remote,
+			localPort: localPort,
+			state:     state,
+			pid:       pid,
+		})
+	}
+	return sockets
+}
+
+// attachConnections adds the sockets accepted on the listening ports of a
+// namespace to those ports, naming the client process on the other end
+// when it runs on this host. A socket goes to the listener owned by the
+// same process when there are several, such as with SO_REUSEPORT.
+func attachConnections(ctx context.Context, ports []Port, namespace string, sockets []tcpSocket) {
+	listeners := make(map[int][]int) // port number to indexes into ports
+	for i, p := range ports {
+		if p.Protocol == "tcp" && p.State == StateListen && p.Namespace == namespace {
+			listeners[p.Number] = append(listeners[p.Number], i)
+		}
+	}
+
+	// The client's end of a local connection is the socket with the
+	// endpoints swapped
+	peers := make(map[string]tcpSocket, len(sockets))
+	for _, s := range sockets {
+		peers[s.local+" "+s.remote] = s
+	}
+	clients := clientResolver{ctx: ctx}
+
+	for _, s := range sockets {
+		candidates := listeners[s.localPort]
+		if len(candidates) == 0 {
+			// Our end of an outgoing connection
+			continue
+		}
+		owner := candidates[0]
+		for _, i := range candidates {
+			if s.pid != 0 && ports[i].PID == s.pid {
+				owner = i
+			}
+		}
+
+		conn := Connection{
+			Local:     s.local,
+			Remote:    s.remote,
+			State:     s.state,
+			SendQueue: s.txQueue,
+			RecvQueue: s.rxQueue,
+		}
+		if peer, ok := peers[s.remote+" "+s.local]; ok {
+			conn.ClientPID, conn.ClientName = clients.resolve(peer)
+		}
+		ports[owner].Connections = append(ports[owner].Connections, conn)
+	}
+
+	for _, indexes := range listeners {
+		for _, i := range indexes {
+			conns := ports[i].Connections
+			sort.SliceStable(conns, func(a, b int) bool { return conns[a].Remote < conns[b].Remote })
+		}
+	}
+}
+
+// clientResolver finds the processes owning client sockets, reading
+// socket ownership and process names at most once per scan
+type clientResolver struct {
+	ctx    context.Context
+	owners map[uint64]int
+	names  map[int]string
+}
+
+// resolve returns the PID and name of the process owning a socket, or
+// zero when it can't be seen
+func (r *clientResolver) resolve(s tcpSocket) (int, string) {
+	pid := s.pid
+	if pid == 0 && s.inode != 0 {
+		if r.owners == nil {
+			// Other users' sockets are only visible to root
+			pids, _ := procPIDs()
+			r.owners = socketOwners(pids)
+		}
+		pid = r.owners[s.inode]
+	}
+	if pid == 0 {
+		return 0, ""
+	}
+	if s.name != "" {
+		return pid, s.name
+	}
+
+	if r.names == nil {
+		r.names = make(map[int]string)
+	}
+	name, ok := r.names[pid]
+	if !ok {
+		if runtime.GOOS == "windows" {
+			name = getProcessNameWindows(r.ctx, pid)
+		} else if info, err := process.Lookup(pid); err == nil {
+			name = info.Name
+		}
+		r.names[pid] = name
+	}
+	return pid, name
+}
+
+// endpoint formats an address as "ip:port", writing IPv4-mapped IPv6
+// addresses as IPv4 so both ends of a dual-stack connection match
+func endpoint(ip net.IP, port int) string {
+	if v4 := ip.To4(); v4 != nil {
+		ip = v4
+	}
+	return net.JoinHostPort(ip.String(), strconv.Itoa(port))
+}
+
+// parseEndpoint normalizes an "ip:port" or "[ip]:port" address as printed
+// by lsof and netstat, dropping any IPv6 zone
+func parseEndpoint(address string) (string, int, bool) {
+	host, portStr, err := net.SplitHostPort(address)
+	if err != nil {
+		return "", 0, false
+	}
+	host, _, _ = strings.Cut(host, "%")
+	ip := net.ParseIP(host)
+	port, err := strconv.Atoi(portStr)
+	if ip == nil || err != nil || port < 1 || port > 65535 {
+		return "", 0, false
+	}
+	return endpoint(ip, port), port, true
+}
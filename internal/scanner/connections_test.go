@@ -0,0 +1,122 @@
+package scanner
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+// procTCP is a /proc/net/tcp table with a listener on 8080, a local client
+// connected to it, a remote client, and a TIME_WAIT socket
+const procTCP = `  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000:1F90 00000000:0000 0A 00000000:00000000 00:00000000 00000000  1000        0 41001 1 0000000000000000 100 0 0 10 0
+   1: 0100007F:1F90 0100007F:D431 01 00000000:00000000 00:00000000 00000000  1000        0 41002 1 0000000000000000 20 4 30 10 -1
+   2: 0100007F:D431 0100007F:1F90 01 00000000:00000000 00:00000000 00000000  1000        0 41003 1 0000000000000000 20 4 30 10 -1
+   3: 1701A8C0:1F90 2A01A8C0:E2F4 01 00000200:00000010 00:00000000 00000000  1000        0 41004 1 0000000000000000 20 4 30 10 -1
+   4: 0100007F:1F90 0100007F:D440 06 00000000:00000000 03:00000a2c 00000000     0        0 0 3 0000000000000000
+`
+
+func TestProcTCPSockets(t *testing.T) {
+	sockets := procTCPSockets(procTCP)
+
+	want := []tcpSocket{
+		{local: "127.0.0.1:8080", remote: "127.0.0.1:54321", localPort: 8080, state: "ESTABLISHED", inode: 41002},
+		{local: "127.0.0.1:54321", remote: "127.0.0.1:8080", localPort: 54321, state: "ESTABLISHED", inode: 41003},
+		{local: "192.168.1.23:8080", remote: "192.168.1.42:58100", localPort: 8080, state: "ESTABLISHED", inode: 41004, txQueue: 512, rxQueue: 16},
+	}
+	if !reflect.DeepEqual(sockets, want) {
+		t.Errorf("procTCPSockets() = %+v, want %+v", sockets, want)
+	}
+}
+
+func TestParseLsofSockets(t *testing.T) {
+	ports, err := parseUnixOutput(readFixture(t, "lsof/hex_devices.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	attachConnections(context.Background(), ports, "", parseLsofSockets(readFixture(t, "lsof/hex_devices.txt")))
+
+	p := FindByPort(ports, 3000)
+	want := []Connection{
+		{Local: "[::1]:3000", Remote: "[::1]:53012", State: "ESTABLISHED"},
+		{Local: "[::1]:3000", Remote: "[::1]:53013", State: "CLOSE_WAIT"},
+	}
+	if p == nil || !reflect.DeepEqual(p.Connections, want) {
+		t.Errorf("connections on port 3000 = %+v, want %+v", p, want)
+	}
+}
+
+func TestParseNetstatSockets(t *testing.T) {
+	capture := readFixture(t, "netstat/scoped_addresses.txt")
+	ports, err := parseWindowsOutput(capture)
+	if err != nil {
+		t.Fatal(err)
+	}
+	attachConnections(context.Background(), ports, "", parseNetstatSockets(capture))
+
+	// The TIME_WAIT socket has no client left
+	p := FindByPort(ports, 5173)
+	if p == nil || len(p.Connections) != 1 {
+		t.Fatalf("connections on port 5173 = %+v, want one", p)
+	}
+	if c := p.Connections[0]; c.Remote != "127.0.0.1:61044" || c.ClientPID != 9876 {
+		t.Errorf("connection = %+v, want one from PID 9876 on 127.0.0.1:61044", c)
+	}
+}
+
+func TestAttachConnections(t *testing.T) {
+	ports := []Port{
+		{Number: 8080, PID: 10, Protocol: "tcp", State: StateListen},
+		{Number: 8080, PID: 11, Protocol: "tcp", State: StateListen},
+		{Number: 8080, PID: 12, Protocol: "tcp", State: StateListen, Namespace: "netns:blue"},
+		{Number: 5353, PID: 13, Protocol: "udp", State: StateUnconnected},
+	}
+	sockets := []tcpSocket{
+		// Accepted by the second listener, from a client on this host
+		{local: "127.0.0.1:8080", remote: "127.0.0.1:40000", localPort: 8080, state: "ESTABLISHED", pid: 11},
+		{local: "127.0.0.1:40000", remote: "127.0.0.1:8080", localPort: 40000, state: "ESTABLISHED", pid: 20, name: "curl"},
+		// Owner unknown, so it goes to the first listener
+		{local: "10.0.0.2:8080", remote: "10.0.0.9:50000", localPort: 8080, state: "CLOSE_WAIT"},
+		// An outgoing connection
+		{local: "10.0.0.2:50001", remote: "1.1.1.1:443", localPort: 50001, state: "ESTABLISHED", pid: 10},
+	}
+	attachConnections(context.Background(), ports, "", sockets)
+
+	counts := make([]int, len(ports))
+	for i, p := range ports {
+		counts[i] = len(p.Connections)
+	}
+	if want := []int{1, 1, 0, 0}; !reflect.DeepEqual(counts, want) {
+		t.Fatalf("connection counts = %v, want %v", counts, want)
+	}
+	if c := ports[1].Connections[0]; c.ClientPID != 20 || c.ClientName != "curl" {
+		t.Errorf("client = %d %q, want 20 curl", c.ClientPID, c.ClientName)
+	}
+	if c := ports[0].Connections[0]; c.ClientPID != 0 || c.State != "CLOSE_WAIT" {
+		t.Errorf("remote connection = %+v", c)
+	}
+}
+
+func TestParseEndpoint(t *testing.T) {
+	tests := []struct {
+		address string
+		want    string
+		port    int
+		ok      bool
+	}{
+		{"127.0.0.1:5173", "127.0.0.1:5173", 5173, true},
+		{"[::1]:3000", "[::1]:3000", 3000, true},
+		{"[::ffff:127.0.0.1]:8080", "127.0.0.1:8080", 8080, true},
+		{"[fe80::1%12]:1900", "[fe80::1]:1900", 1900, true},
+		{"*:*", "", 0, false},
+		{"0.0.0.0:0", "", 0, false},
+		{"localhost:80", "", 0, false},
+	}
+
+	for _, tt := range tests {
+		got, port, ok := parseEndpoint(tt.address)
+		if got != tt.want || port != tt.port || ok != tt.ok {
+			t.Errorf("parseEndpoint(%q) = %q, %d, %v; want %q, %d, %v", tt.address, got, port, ok, tt.want, tt.port, tt.ok)
+		}
+	}
+}
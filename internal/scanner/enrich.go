@@ -0,0 +1,180 @@
+package scanner
+
+import (
+	"context"
+	"runtime"
+	"strings"
+
+	"github.com/NoaTamburrini/portman/internal/container"
+	"github.com/NoaTamburrini/portman/internal/process"
+	"github.com/NoaTamburrini/portman/internal/project"
+	"github.com/NoaTamburrini/portman/internal/systemd"
+)
+
+// enrich adds information that the port listing tools don't report,
+// skipping the lookups left once the context is done. sockets are the
+// connections listed by the backend, as returned by scan.
+func enrich(ctx context.Context, ports []Port, sockets []tcpSocket) {
+	pids := uniquePIDs(ports)
+	stages := []func(){
+		func() { enrichStartTimes(ctx, ports, pids) },
+		func() { enrichUsers(ctx, ports, pids) },
+		func() { enrichPaused(ctx, ports, pids) },
+		func() { enrichProjects(ctx, ports, pids) },
+		func() { enrichContainers(ctx, ports) },
+		func() { enrichConnections(ctx, ports, sockets) },
+	}
+	if runtime.GOOS == "linux" {
+		stages = append(stages, func() { enrichUnits(ctx, ports) })
+	}
+
+	for _, stage := range stages {
+		if ctx.Err() != nil {
+			return
+		}
+		stage()
+	}
+}
+
+// uniquePIDs lists the PIDs owning the ports, once each
+func uniquePIDs(ports []Port) []int {
+	seen := make(map[int]bool)
+	var pids []int
+	for _, p := range ports {
+		if !seen[p.PID] {
+			seen[p.PID] = true
+			pids = append(pids, p.PID)
+		}
+	}
+	return pids
+}
+
+// enrichStartTimes records when each port's owner started
+func enrichStartTimes(ctx context.Context, ports []Port, pids []int) {
+	times := process.StartTimes(ctx, pids)
+	for i := range ports {
+		ports[i].StartTime = times[ports[i].PID]
+	}
+}
+
+// enrichUsers records the user owning each port's process
+func enrichUsers(ctx context.Context, ports []Port, pids []int) {
+	users := process.Users(ctx, pids)
+	for i := range ports {
+		ports[i].User = users[ports[i].PID]
+	}
+}
+
+// enrichPaused flags ports whose process is suspended
+func enrichPaused(ctx context.Context, ports []Port, pids []int) {
+	paused := process.Paused(ctx, pids)
+	for i := range ports {
+		ports[i].Paused = paused[ports[i].PID]
+	}
+}
+
+// enrichProjects records each port owner's working directory and the
+// checkout it belongs to
+func enrichProjects(ctx context.Context, ports []Port, pids []int) {
+	cwds := process.Cwds(ctx, pids)
+	checkouts := make(map[string]*project.Checkout)
+
+	for i := range ports {
+		p := &ports[i]
+		p.Cwd = cwds[p.PID]
+		if p.Cwd == "" {
+			continue
+		}
+
+		checkout, seen := checkouts[p.Cwd]
+		if !seen {
+			if c, ok := project.Detect(p.Cwd); ok {
+				checkout = &c
+			}
+			checkouts[p.Cwd] = checkout
+		}
+		p.Project = checkout
+	}
+}
+
+// enrichContainers tags ports published by Docker or Podman containers
+func enrichContainers(ctx context.Context, ports []Port) {
+	published := container.PublishedPorts(ctx)
+	if len(published) == 0 {
+		return
+	}
+	tagContainers(ports, published)
+}
+
+// tagContainers tags the ports that publish a container. Published ports
+// show up as owned by the runtime's proxy rather than the container, so a
+// port is only tagged when a proxy holds it on the published address;
+// anything else on the same port number is an unrelated process.
+func tagContainers(ports []Port, published map[container.Key]container.Container) {
+	for i, p := range ports {
+		if p.Namespace != "" || !runtimeProxy(p.ProcessName) {
+			continue
+		}
+		key := container.Key{Port: p.Number, Protocol: p.Protocol, IP: p.Address}
+		if c, ok := published[key]; ok {
+			ports[i].Container = &c
+		}
+	}
+}
+
+// runtimeProxies are the processes that hold published container ports on
+// the host: Docker's userland proxy, Docker Desktop, and rootless Podman's
+// port forwarders
+var runtimeProxies = []string{
+	"docker-proxy",
+	"com.docker.backend",
+	"rootlessport",
+	"conmon",
+	"pasta",
+	"pasta.avx2",
+	"slirp4netns",
+	"gvproxy",
+}
+
+// runtimeProxy reports whether name is one of runtimeProxies. lsof cuts
+// names to 9 characters, so a name that long matches a longer proxy name
+// it starts.
+func runtimeProxy(name string) bool {
+	name = strings.TrimSuffix(name, ".exe")
+	for _, proxy := range runtimeProxies {
+		if name == proxy || (len(name) >= 9 && strings.HasPrefix(proxy, name)) {
+			return true
+		}
+	}
+	return false
+}
+
+// enrichUnits tags ports with the systemd unit supervising their owner
+func enrichUnits(ctx context.Context, ports []Port) {
+	units := make(map[int]*systemd.Unit)
+	var sockets map[int]systemd.Unit
+
+	for i := range ports {
+		p := &ports[i]
+
+		// Socket-activated ports are held by systemd until the service starts
+		if p.PID == 1 || p.ProcessName == "systemd" {
+			if sockets == nil {
+				sockets = systemd.SocketUnits(ctx)
+			}
+			if unit, ok := sockets[p.Number]; ok {
+				p.Unit = &unit
+				continue
+			}
+		}
+
+		unit, seen := units[p.PID]
+		if !seen {
+			if u, ok := systemd.UnitForPID(p.PID); ok {
+				unit = &u
+			}
+			units[p.PID] = unit
+		}
+		p.Unit = unit
+	}
+}
@@ -0,0 +1,210 @@
+package scanner
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var allNamespaces bool
+
+// SetAllNamespaces makes ScanPorts also list ports in other network
+// namespaces on Linux, such as containers and `ip netns` sandboxes
+func SetAllNamespaces(enabled bool) {
+	allNamespaces = enabled
+}
+
+// AllNamespaces reports whether ScanPorts lists other network namespaces
+func AllNamespaces() bool {
+	return allNamespaces
+}
+
+// containerCgroup matches the container ID in a cgroup path written by
+// docker, containerd, podman and CRI-O
+var containerCgroup = regexp.MustCompile(`(?:docker|libpod|crio|cri-containerd)[-/]([0-9a-f]{12,64})`)
+
+// scanNamespaces lists listening sockets in every network namespace other
+// than portman's own, by reading each namespace's /proc/<pid>/net tables.
+// Processes of other users are only visible when running as root.
+// It stops with the context's error once the context is done.
+func scanNamespaces(ctx context.Context) ([]Port, error) {
+	self, err := os.Readlink("/proc/self/ns/net")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read network namespace: %w", err)
+	}
+
+	pids, err := procPIDs()
+	if err != nil {
+		return nil, err
+	}
+
+	// Group processes by network namespace
+	members := make(map[string][]int)
+	for _, pid := range pids {
+		ns, err := os.Readlink(fmt.Sprintf("/proc/%d/ns/net", pid))
+		if err != nil || ns == self {
+			continue
+		}
+		members[ns] = append(members[ns], pid)
+	}
+
+	names := namedNamespaces()
+
+	var ports []Port
+	for ns, nsPIDs := range members {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		owners := socketOwners(nsPIDs)
+		label := namespaceLabel(ns, nsPIDs[0], names)
+
+		for _, table := range []string{"tcp", "tcp6", "udp", "udp6"} {
+			content, err := os.ReadFile(fmt.Sprintf("/proc/%d/net/%s", nsPIDs[0], table))
+			if err != nil {
+				continue
+			}
+
+			for _, s := range parseProcNet(string(content), strings.TrimSuffix(table, "6")) {
+				if s.State != tcpListen && s.State != udpUnconnected {
+					continue
+				}
+				pid, ok := owners[s.Inode]
+				if !ok {
+					continue
+				}
+				state := StateListen
+				if s.Protocol == "udp" {
+					state = StateUnconnected
+				}
+				name, command := procName(pid)
+				ports = append(ports, Port{
+					Number:      s.LocalPort,
+					PID:         pid,
+					ProcessName: name,
+					Command:     command,
+					Protocol:    s.Protocol,
+					Address:     bindHost(s.LocalIP.String()),
+					State:       state,
+					Namespace:   label,
+				})
+			}
+		}
+	}
+
+	return dedupe(ports), nil
+}
+
+// procPIDs lists the numeric entries of /proc
+func procPIDs() ([]int, error) {
+	entries, err := os.ReadDir("/proc")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read /proc: %w", err)
+	}
+
+	var pids []int
+	for _, e := range entries {
+		if pid, err := strconv.Atoi(e.Name()); err == nil {
+			pids = append(pids, pid)
+		}
+	}
+	return pids, nil
+}
+
+// socketOwners maps socket inodes to the PIDs holding them open
+func socketOwners(pids []int) map[uint64]int {
+	owners := make(map[uint64]int)
+	for _, pid := range pids {
+		fdDir := fmt.Sprintf("/proc/%d/fd", pid)
+		fds, err := os.ReadDir(fdDir)
+		if err != nil {
+			continue
+		}
+		for _, fd := range fds {
+			link, err := os.Readlink(filepath.Join(fdDir, fd.Name()))
+			if err != nil || !strings.HasPrefix(link, "socket:[") {
+				continue
+			}
+			inode, err := strconv.ParseUint(strings.TrimSuffix(strings.TrimPrefix(link, "socket:["), "]"), 10, 64)
+			if err != nil {
+				continue
+			}
+			if _, seen := owners[inode]; !seen {
+				owners[inode] = pid
+			}
+		}
+	}
+	return owners
+}
+
+// namedNamespaces returns the `ip netns` namespaces, which are bind
+// mounts of the namespace files under /run/netns
+func namedNamespaces() map[string]os.FileInfo {
+	names := make(map[string]os.FileInfo)
+	entries, err := os.ReadDir("/run/netns")
+	if err != nil {
+		return names
+	}
+
+	for _, e := range entries {
+		if info, err := os.Stat(filepath.Join("/run/netns", e.Name())); err == nil {
+			names[e.Name()] = info
+		}
+	}
+	return names
+}
+
+// namespaceLabel describes a namespace by its `ip netns` name, the
+// container one of its processes belongs to, or its inode
+func namespaceLabel(ns string, pid int, names map[string]os.FileInfo) string {
+	if len(names) > 0 {
+		if info, err := os.Stat(fmt.Sprintf("/proc/%d/ns/net", pid)); err == nil {
+			for name, named := range names {
+				if os.SameFile(info, named) {
+					return "netns:" + name
+				}
+			}
+		}
+	}
+
+	if cgroup, err := os.ReadFile(fmt.Sprintf("/proc/%d/cgroup", pid)); err == nil {
+		if m := containerCgroup.FindStringSubmatch(string(cgroup)); m != nil {
+			return "container:" + m[1][:12]
+		}
+	}
+
+	return ns
+}
+
+// procName returns a process's name and command line from /proc
+func procName(pid int) (string, string) {
+	name := "unknown"
+	if comm, err := os.ReadFile(fmt.Sprintf("/proc/%d/comm", pid)); err == nil {
+		name = strings.TrimSpace(string(comm))
+	}
+
+	command := name
+	if cmdline, err := os.ReadFile(fmt.Sprintf("/proc/%d/cmdline", pid)); err == nil && len(cmdline) > 0 {
+		// Arguments are NUL-separated and may themselves contain newlines
+		command = strings.Join(strings.Fields(strings.ReplaceAll(string(cmdline), "\x00", " ")), " ")
+	}
+
+	return name, command
+}
+
+// dedupe removes duplicate ports, such as a socket listed in both tcp and tcp6
+func dedupe(ports []Port) []Port {
+	seen := make(map[string]bool)
+	unique := ports[:0]
+	for _, p := range ports {
+		key := fmt.Sprintf("%s-%d-%d-%s", p.Protocol, p.Number, p.PID, p.Namespace)
+		if !seen[key] {
+			seen[key] = true
+			unique = append(unique, p)
+		}
+	}
+	return unique
+}
@@ -1,5 +1,14 @@
 package scanner
 
+import (
+	"time"
+
+	"github.com/NoaTamburrini/portman/internal/container"
+	"github.com/NoaTamburrini/portman/internal/process"
+	"github.com/NoaTamburrini/portman/internal/project"
+	"github.com/NoaTamburrini/portman/internal/systemd"
+)
+
 // Port represents information about a port and its associated process
 type Port struct {
 	Number      int    `json:"port"`
@@ -7,4 +16,71 @@ type Port struct {
 	ProcessName string `json:"process"`
 	Command     string `json:"command"`
 	Protocol    string `json:"protocol"`
+	// Address is the local IP the socket is bound to, "*" for all of them
+	Address string `json:"address,omitempty"`
+	// User owns the process, when it can be determined
+	User string `json:"user,omitempty"`
+	// State is the TCP state, such as LISTEN or ESTABLISHED. UDP is
+	// connectionless: bound sockets are UNCONN, and ESTABLISHED only
+	// for sockets connected to a single peer. ScanPorts only returns
+	// listening sockets.
+	State string `json:"state,omitempty"`
+	// Connections lists the client connections accepted on a listening
+	// TCP port
+	Connections []Connection `json:"connections,omitempty"`
+	// Paused is set while the owning process is suspended
+	Paused bool `json:"paused,omitempty"`
+	// Container is set when the port is published by a Docker or Podman container
+	Container *container.Container `json:"container,omitempty"`
+	// Namespace is set for ports in a network namespace other than portman's
+	// own: "netns:<name>", "container:<id>", or "net:[<inode>]"
+	Namespace string `json:"namespace,omitempty"`
+	// Unit is the systemd unit supervising the process, or the socket unit
+	// for socket-activated ports held by systemd itself
+	Unit *systemd.Unit `json:"unit,omitempty"`
+	// Usage is the owner's resource usage, set by AddUsage
+	Usage *process.Usage `json:"usage,omitempty"`
+	// StartTime is when the owning process started, zero when unknown
+	StartTime time.Time `json:"start_time,omitzero"`
+	// Cwd is the owning process's working directory
+	Cwd string `json:"cwd,omitempty"`
+	// Project is the checkout enclosing Cwd, if any
+	Project *project.Checkout `json:"project,omitempty"`
+}
+
+// Socket states reported in Port.State, named as lsof and ss name them
+const (
+	StateListen      = "LISTEN"
+	StateEstablished = "ESTABLISHED"
+	// StateUnconnected is a UDP socket that is bound but not connected,
+	// the UDP equivalent of a listener
+	StateUnconnected = "UNCONN"
+)
+
+// Listening reports whether the port accepts new traffic: a listening TCP
+// socket or an unconnected UDP socket
+func (p Port) Listening() bool {
+	return p.State == StateListen || p.State == StateUnconnected
+}
+
+// Uptime returns how long the owning process has been running, or 0 when
+// its start time is unknown
+func (p Port) Uptime() time.Duration {
+	if p.StartTime.IsZero() {
+		return 0
+	}
+	return time.Since(p.StartTime)
+}
+
+// StopRecord describes the owning process for the audit log, before it is
+// stopped through what stopped names, its unit or container
+func (p Port) StopRecord(stopped string) process.KillRecord {
+	return process.KillRecord{
+		Time:    time.Now(),
+		PID:     p.PID,
+		Port:    p.Number,
+		Name:    p.ProcessName,
+		Command: p.Command,
+		Stopped: stopped,
+	}
 }
@@ -0,0 +1,127 @@
+package scanner
+
+import (
+	"encoding/hex"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// TCP states as reported in /proc/net/tcp
+const (
+	tcpEstablished = "01"
+	tcpTimeWait    = "06"
+	tcpListen      = "0A"
+	udpUnconnected = "07"
+)
+
+// procTCPStates names the /proc/net/tcp states as lsof names them
+var procTCPStates = map[string]string{
+	"01": StateEstablished,
+	"02": "SYN_SENT",
+	"03": "SYN_RECV",
+	"04": "FIN_WAIT1",
+	"05": "FIN_WAIT2",
+	"06": "TIME_WAIT",
+	"07": "CLOSED",
+	"08": "CLOSE_WAIT",
+	"09": "LAST_ACK",
+	"0A": StateListen,
+	"0B": "CLOSING",
+}
+
+// procSocket is one row of /proc/<pid>/net/{tcp,tcp6,udp,udp6}
+type procSocket struct {
+	Protocol   string
+	LocalIP    net.IP
+	LocalPort  int
+	RemoteIP   net.IP
+	RemotePort int
+	State      string
+	TxQueue    int
+	RxQueue    int
+	UID        int
+	Inode      uint64
+}
+
+// parseProcNet parses the contents of a /proc/net socket table
+func parseProcNet(content, protocol string) []procSocket {
+	lines := strings.Split(content, "\n")
+	if len(lines) < 2 {
+		return nil
+	}
+
+	var sockets []procSocket
+	for _, line := range lines[1:] { // Skip header
+		fields := strings.Fields(line)
+		if len(fields) < 10 {
+			continue
+		}
+
+		localIP, localPort, err := parseProcAddress(fields[1])
+		if err != nil {
+			continue
+		}
+		remoteIP, remotePort, err := parseProcAddress(fields[2])
+		if err != nil {
+			continue
+		}
+
+		var txQueue, rxQueue int64
+		if queues := strings.Split(fields[4], ":"); len(queues) == 2 {
+			txQueue, _ = strconv.ParseInt(queues[0], 16, 64)
+			rxQueue, _ = strconv.ParseInt(queues[1], 16, 64)
+		}
+
+		uid, _ := strconv.Atoi(fields[7])
+		inode, err := strconv.ParseUint(fields[9], 10, 64)
+		if err != nil {
+			continue
+		}
+
+		sockets = append(sockets, procSocket{
+			Protocol:   protocol,
+			LocalIP:    localIP,
+			LocalPort:  localPort,
+			RemoteIP:   remoteIP,
+			RemotePort: remotePort,
+			State:      fields[3],
+			TxQueue:    int(txQueue),
+			RxQueue:    int(rxQueue),
+			UID:        uid,
+			Inode:      inode,
+		})
+	}
+
+	return sockets
+}
+
+// parseProcAddress parses "0100007F:1F90" into an IP and port. The kernel
+// prints addresses as native-endian 32-bit words, little-endian on every
+// platform portman supports.
+func parseProcAddress(s string) (net.IP, int, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 2 {
+		return nil, 0, fmt.Errorf("invalid address %q", s)
+	}
+
+	raw, err := hex.DecodeString(parts[0])
+	if err != nil || (len(raw) != 4 && len(raw) != 16) {
+		return nil, 0, fmt.Errorf("invalid address %q", s)
+	}
+
+	ip := make(net.IP, len(raw))
+	for word := 0; word < len(raw); word += 4 {
+		for i := 0; i < 4; i++ {
+			ip[word+i] = raw[word+3-i]
+		}
+	}
+
+	port, err := strconv.ParseUint(parts[1], 16, 16)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid port in %q", s)
+	}
+
+	return ip, int(port), nil
+}
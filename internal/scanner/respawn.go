@@ -0,0 +1,46 @@
+package scanner
+
+import (
+	"fmt"
+
+	"github.com/NoaTamburrini/portman/internal/process"
+)
+
+// Respawn is a killed process that came back on the same port
+type Respawn struct {
+	// Port is the new process holding the port
+	Port Port
+	// Supervisor is the process manager that restarted it, if known
+	Supervisor *process.Info
+}
+
+// DetectRespawn looks for a process that took over a killed process's port
+// with the same command, which usually means a supervisor restarted it
+func DetectRespawn(killed Port, ports []Port) *Respawn {
+	for _, p := range ports {
+		if p.Number != killed.Number || p.Protocol != killed.Protocol || p.PID == killed.PID {
+			continue
+		}
+		if p.ProcessName != killed.ProcessName || p.Command != killed.Command {
+			continue
+		}
+
+		r := &Respawn{Port: p}
+		if supervisor, ok := process.FindSupervisor(p.PID); ok {
+			r.Supervisor = &supervisor
+		}
+		return r
+	}
+	return nil
+}
+
+// Restarter describes what restarted the process
+func (r *Respawn) Restarter() string {
+	if u := r.Port.Unit; u != nil && u.Restarts() {
+		return "systemd unit " + u.Name
+	}
+	if r.Supervisor != nil {
+		return fmt.Sprintf("%s (PID %d)", r.Supervisor.Name, r.Supervisor.PID)
+	}
+	return "an unknown supervisor"
+}
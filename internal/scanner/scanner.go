@@ -1,43 +1,184 @@
 package scanner
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net/netip"
 	"os/exec"
 	"runtime"
 	"strconv"
 	"strings"
+	"time"
 )
 
+// Backends lists the names accepted by SetBackend
+var Backends = []string{"auto", "lsof", "ss", "netstat"}
+
+var backend = "auto"
+
+// SetBackend selects the tool used to scan ports; "auto" picks one for the OS
+func SetBackend(name string) error {
+	for _, b := range Backends {
+		if name == b {
+			backend = name
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown scanner backend %q (expected one of %s)", name, strings.Join(Backends, ", "))
+}
+
+// Timing breaks down how long a scan took
+type Timing struct {
+	// Exec is spent running the backend, and reading other namespaces'
+	// socket tables with --all-netns
+	Exec time.Duration
+	// Parse is spent parsing the backend's output
+	Parse time.Duration
+	// Enrich is spent looking up process names, start times, containers,
+	// connections and the like
+	Enrich time.Duration
+}
+
+// Total is how long the whole scan took
+func (t Timing) Total() time.Duration {
+	return t.Exec + t.Parse + t.Enrich
+}
+
 // ScanPorts scans for all active ports on the system
 func ScanPorts() ([]Port, error) {
+	return ScanPortsContext(context.Background())
+}
+
+// ScanPortsContext scans for all active ports, killing the backend and
+// returning the context's error if it is done first
+func ScanPortsContext(ctx context.Context) ([]Port, error) {
+	ports, _, err := ScanPortsTimed(ctx)
+	return ports, err
+}
+
+// ScanPortsTimed scans like ScanPortsContext, and reports how long each
+// stage of the scan took
+func ScanPortsTimed(ctx context.Context) ([]Port, Timing, error) {
+	var t Timing
+	ports, sockets, err := scan(ctx, &t)
+	if err != nil {
+		return nil, t, err
+	}
+
+	if allNamespaces && runtime.GOOS == "linux" {
+		start := time.Now()
+		nsPorts, err := scanNamespaces(ctx)
+		t.Exec += time.Since(start)
+		if err != nil {
+			return nil, t, err
+		}
+		ports = append(ports, nsPorts...)
+	}
+
+	ports = listening(ports)
+	start := time.Now()
+	enrich(ctx, ports, sockets)
+	t.Enrich += time.Since(start)
+
+	// A scan that outlived its context is stale to whoever gave up on it
+	if err := ctx.Err(); err != nil {
+		return nil, t, err
+	}
+	return ports, t, nil
+}
+
+// listening keeps the listening sockets. The other rows the backends list
+// are our ends of outgoing connections, whose ephemeral ports aren't worth
+// listing, and the connections accepted on a listener, which enrich
+// attaches to it.
+func listening(ports []Port) []Port {
+	var listeners []Port
+	for _, p := range ports {
+		if p.Listening() {
+			listeners = append(listeners, p)
+		}
+	}
+	return listeners
+}
+
+// scan lists ports using the selected backend. Except on Linux, where
+// connections are read from /proc with their queues, it also returns the
+// TCP connections the backend listed, for enrich to attach to listeners
+// without running it again.
+func scan(ctx context.Context, t *Timing) ([]Port, []tcpSocket, error) {
+	switch backend {
+	case "lsof":
+		return scanPortsUnix(ctx, t)
+	case "ss":
+		return scanPortsSS(ctx, t)
+	case "netstat":
+		return scanPortsWindows(ctx, t)
+	}
+
 	switch runtime.GOOS {
-	case "darwin", "linux":
-		return scanPortsUnix()
+	case "darwin":
+		return scanPortsUnix(ctx, t)
+	case "linux":
+		// Minimal distributions and containers often ship ss but not lsof
+		if _, err := exec.LookPath("lsof"); err != nil {
+			if _, err := exec.LookPath("ss"); err == nil {
+				return scanPortsSS(ctx, t)
+			}
+		}
+		return scanPortsUnix(ctx, t)
 	case "windows":
-		return scanPortsWindows()
+		return scanPortsWindows(ctx, t)
 	default:
-		return nil, fmt.Errorf("unsupported operating system: %s", runtime.GOOS)
+		return nil, nil, fmt.Errorf("unsupported operating system: %s", runtime.GOOS)
 	}
 }
 
+// runBackend runs a backend's command, adding its run time to t.Exec. It
+// is killed when the context is done, and the context's error returned.
+func runBackend(ctx context.Context, t *Timing, name string, args ...string) ([]byte, error) {
+	start := time.Now()
+	output, err := exec.CommandContext(ctx, name, args...).Output()
+	t.Exec += time.Since(start)
+
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return nil, ctxErr
+	}
+	if err != nil {
+		return output, fmt.Errorf("failed to execute %s: %w", name, err)
+	}
+	return output, nil
+}
+
 // scanPortsUnix uses lsof to scan ports on macOS and Linux
-func scanPortsUnix() ([]Port, error) {
-	cmd := exec.Command("lsof", "-i", "-P", "-n")
-	output, err := cmd.Output()
+func scanPortsUnix(ctx context.Context, t *Timing) ([]Port, []tcpSocket, error) {
+	output, err := runBackend(ctx, t, "lsof", "-i", "-P", "-n")
 	if err != nil {
 		// lsof returns non-zero exit code when no processes found
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			if len(exitErr.Stderr) == 0 {
-				return []Port{}, nil
-			}
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(exitErr.Stderr) == 0 {
+			return []Port{}, nil, nil
 		}
-		return nil, fmt.Errorf("failed to execute lsof: %w", err)
+		return nil, nil, err
 	}
 
-	return parseUnixOutput(string(output))
+	start := time.Now()
+	ports, err := parseUnixOutput(string(output))
+	var sockets []tcpSocket
+	if runtime.GOOS != "linux" {
+		sockets = parseLsofSockets(string(output))
+	}
+	t.Parse += time.Since(start)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ports, sockets, nil
 }
 
-// parseUnixOutput parses the output from lsof
+// parseUnixOutput parses the output of `lsof -i -P -n`, whose rows are
+// "COMMAND PID USER FD TYPE DEVICE SIZE/OFF NODE NAME [(STATE)]". The
+// NAME of a connection is "local->remote". Command is set to the process
+// name; lsof has no command line.
 func parseUnixOutput(output string) ([]Port, error) {
 	lines := strings.Split(output, "\n")
 	if len(lines) < 2 {
@@ -47,148 +188,272 @@ func parseUnixOutput(output string) ([]Port, error) {
 	portMap := make(map[string]Port) // Use map to deduplicate
 
 	for _, line := range lines[1:] { // Skip header
-		if line == "" {
+		row, ok := parseLsofRow(line)
+		if !ok {
 			continue
 		}
 
-		fields := strings.Fields(line)
-		if len(fields) < 9 {
+		port, ok := localPort(row.address)
+		if !ok {
 			continue
 		}
 
-		processName := fields[0]
-		pidStr := fields[1]
-		protocol := strings.ToLower(fields[7])
-		address := fields[8]
-
-		// Parse PID
-		pid, err := strconv.Atoi(pidStr)
-		if err != nil {
-			continue
+		state := row.state
+		if row.protocol == "udp" {
+			state = udpState(strings.Contains(row.address, "->"))
 		}
 
-		// Extract port number from address
-		var port int
-		if strings.Contains(address, ":") {
-			parts := strings.Split(address, ":")
-			if len(parts) >= 2 {
-				portStr := parts[len(parts)-1]
-				// Remove any trailing characters like (LISTEN)
-				portStr = strings.Split(portStr, "(")[0]
-				port, err = strconv.Atoi(portStr)
-				if err != nil {
-					continue
-				}
-			}
-		}
+		addPort(portMap, Port{
+			Number:      port,
+			PID:         row.pid,
+			ProcessName: row.name,
+			Command:     row.name,
+			Protocol:    row.protocol,
+			Address:     localHost(row.address),
+			State:       state,
+		})
+	}
 
-		if port == 0 {
-			continue
-		}
+	return portList(portMap), nil
+}
 
-		// Get command (rest of the line)
-		command := processName
-		if len(fields) > 9 {
-			command = strings.Join(fields[9:], " ")
+// lsofRow is one socket listed by lsof
+type lsofRow struct {
+	name     string
+	pid      int
+	protocol string
+	// address is "local" or, for connections, "local->remote"
+	address string
+	// state is the TCP state, empty for UDP
+	state string
+}
+
+// parseLsofRow parses a row of `lsof -i -P -n` output
+func parseLsofRow(line string) (lsofRow, bool) {
+	fields := strings.Fields(line)
+
+	// Find the NODE column rather than trusting its index, in case a
+	// process name contains unescaped spaces
+	node := -1
+	for i := 7; i < len(fields)-1; i++ {
+		if fields[i] == "TCP" || fields[i] == "UDP" {
+			node = i
+			break
 		}
+	}
+	if node < 0 {
+		return lsofRow{}, false
+	}
 
-		// Create unique key for deduplication
-		key := fmt.Sprintf("%s-%d-%d", protocol, port, pid)
+	pid, err := strconv.Atoi(fields[node-6])
+	if err != nil || pid <= 0 {
+		return lsofRow{}, false
+	}
 
-		portMap[key] = Port{
-			Number:      port,
-			PID:         pid,
-			ProcessName: processName,
-			Command:     command,
-			Protocol:    protocol,
+	row := lsofRow{
+		name:     unescapeLsof(strings.Join(fields[:node-6], " ")),
+		pid:      pid,
+		protocol: strings.ToLower(fields[node]),
+		address:  fields[node+1],
+	}
+
+	// TCP rows end with the state in parentheses; UDP rows have none
+	if last := fields[len(fields)-1]; strings.HasPrefix(last, "(") && strings.HasSuffix(last, ")") {
+		row.state = strings.Trim(last, "()")
+	}
+	return row, true
+}
+
+// localPort extracts the local port from an address such as
+// "127.0.0.1:3000", "[::1]:3000", "*:3000" or "10.0.0.2:51234->1.1.1.1:443".
+// Wildcard ports ("*:*") and port 0 are rejected.
+func localPort(address string) (int, bool) {
+	local, _, _ := strings.Cut(address, "->")
+	i := strings.LastIndex(local, ":")
+	if i < 0 {
+		return 0, false
+	}
+
+	port, err := strconv.Atoi(local[i+1:])
+	if err != nil || port < 1 || port > 65535 {
+		return 0, false
+	}
+	return port, true
+}
+
+// localHost extracts the local IP from an address in any of the forms
+// localPort accepts, dropping brackets and zones
+func localHost(address string) string {
+	local, _, _ := strings.Cut(address, "->")
+	i := strings.LastIndex(local, ":")
+	if i < 0 {
+		return ""
+	}
+
+	host, _, _ := strings.Cut(local[:i], "%")
+	return bindHost(strings.Trim(host, "[]"))
+}
+
+// bindHost shows an address bound to every interface ("0.0.0.0" or "::")
+// as "*", the way lsof does, and IPv4-mapped IPv6 addresses as IPv4, so
+// that all backends agree
+func bindHost(ip string) string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ip
+	}
+	if addr.IsUnspecified() {
+		return "*"
+	}
+	return addr.Unmap().String()
+}
+
+// unescapeLsof decodes the \xNN escapes lsof uses for spaces and other
+// unprintable bytes in process names, e.g. "Code\x20H"
+func unescapeLsof(name string) string {
+	if !strings.Contains(name, `\x`) {
+		return name
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(name); i++ {
+		if name[i] == '\\' && i+3 < len(name) && name[i+1] == 'x' {
+			if c, err := strconv.ParseUint(name[i+2:i+4], 16, 8); err == nil {
+				b.WriteByte(byte(c))
+				i += 3
+				continue
+			}
 		}
+		b.WriteByte(name[i])
+	}
+	return b.String()
+}
+
+// udpState names the state of a UDP socket, which is only ever connected
+// to a peer or not
+func udpState(connected bool) string {
+	if connected {
+		return StateEstablished
 	}
+	return StateUnconnected
+}
+
+// addPort adds a port to a deduplicating map keyed by protocol, port and
+// PID. A process listening on a port usually also has connections on it;
+// the listening socket is the one kept.
+func addPort(portMap map[string]Port, p Port) {
+	key := fmt.Sprintf("%s-%d-%d", p.Protocol, p.Number, p.PID)
+	if existing, ok := portMap[key]; ok && existing.Listening() && !p.Listening() {
+		return
+	}
+	portMap[key] = p
+}
 
-	// Convert map to slice
+// portList converts a deduplicating map back to a slice
+func portList(portMap map[string]Port) []Port {
 	ports := make([]Port, 0, len(portMap))
 	for _, port := range portMap {
 		ports = append(ports, port)
 	}
-
-	return ports, nil
+	return ports
 }
 
 // scanPortsWindows uses netstat to scan ports on Windows
-func scanPortsWindows() ([]Port, error) {
-	cmd := exec.Command("netstat", "-ano")
-	output, err := cmd.Output()
+func scanPortsWindows(ctx context.Context, t *Timing) ([]Port, []tcpSocket, error) {
+	output, err := runBackend(ctx, t, "netstat", "-ano")
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute netstat: %w", err)
+		return nil, nil, err
 	}
 
-	return parseWindowsOutput(string(output))
-}
+	start := time.Now()
+	ports, err := parseWindowsOutput(string(output))
+	var sockets []tcpSocket
+	if runtime.GOOS != "linux" {
+		sockets = parseNetstatSockets(string(output))
+	}
+	t.Parse += time.Since(start)
+	if err != nil {
+		return nil, nil, err
+	}
 
-// parseWindowsOutput parses the output from netstat on Windows
-func parseWindowsOutput(output string) ([]Port, error) {
-	lines := strings.Split(output, "\n")
-	if len(lines) < 4 {
-		return []Port{}, nil
+	// Get process names from PIDs (Windows specific), once per process
+	start = time.Now()
+	defer func() { t.Enrich += time.Since(start) }()
+	names := make(map[int]string)
+	for i := range ports {
+		// tasklist runs once per process, which adds up on busy hosts
+		if err := ctx.Err(); err != nil {
+			return nil, nil, err
+		}
+		name, ok := names[ports[i].PID]
+		if !ok {
+			name = getProcessNameWindows(ctx, ports[i].PID)
+			names[ports[i].PID] = name
+		}
+		ports[i].ProcessName = name
+		ports[i].Command = name
 	}
+	return ports, sockets, nil
+}
 
+// parseWindowsOutput parses the output from `netstat -ano` on Windows. TCP
+// rows are "Proto Local Foreign State PID"; UDP rows have no state column.
+// Process names are left for the caller to fill in.
+func parseWindowsOutput(output string) ([]Port, error) {
 	portMap := make(map[string]Port)
 
-	for _, line := range lines[4:] { // Skip headers
-		if line == "" {
-			continue
-		}
-
+	for _, line := range strings.Split(output, "\n") {
 		fields := strings.Fields(line)
-		if len(fields) < 5 {
+		if len(fields) < 4 {
 			continue
 		}
 
+		// Skips the title and header lines, which are localized
 		protocol := strings.ToLower(fields[0])
-		localAddress := fields[1]
-		pidStr := fields[len(fields)-1]
-
-		// Parse PID
-		pid, err := strconv.Atoi(pidStr)
-		if err != nil {
+		if protocol != "tcp" && protocol != "udp" {
 			continue
 		}
 
-		// Extract port from local address
-		parts := strings.Split(localAddress, ":")
-		if len(parts) < 2 {
+		var state string
+		switch {
+		case protocol == "tcp" && len(fields) == 5:
+			state = fields[3]
+			if state == "LISTENING" {
+				state = StateListen
+			}
+		case protocol == "udp" && len(fields) == 4:
+			// netstat only lists bound UDP endpoints, with "*:*" as the peer
+			state = udpState(fields[2] != "*:*")
+		default:
 			continue
 		}
 
-		port, err := strconv.Atoi(parts[len(parts)-1])
-		if err != nil || port == 0 {
+		// PID 0 is the idle process, which owns TIME_WAIT sockets
+		pid, err := strconv.Atoi(fields[len(fields)-1])
+		if err != nil || pid <= 0 {
 			continue
 		}
 
-		// Get process name from PID (Windows specific)
-		processName := getProcessNameWindows(pid)
-
-		key := fmt.Sprintf("%s-%d-%d", protocol, port, pid)
-
-		portMap[key] = Port{
-			Number:      port,
-			PID:         pid,
-			ProcessName: processName,
-			Command:     processName,
-			Protocol:    protocol,
+		port, ok := localPort(fields[1])
+		if !ok {
+			continue
 		}
-	}
 
-	ports := make([]Port, 0, len(portMap))
-	for _, port := range portMap {
-		ports = append(ports, port)
+		addPort(portMap, Port{
+			Number:   port,
+			PID:      pid,
+			Protocol: protocol,
+			Address:  localHost(fields[1]),
+			State:    state,
+		})
 	}
 
-	return ports, nil
+	return portList(portMap), nil
 }
 
 // getProcessNameWindows gets the process name from PID on Windows
-func getProcessNameWindows(pid int) string {
-	cmd := exec.Command("tasklist", "/FI", fmt.Sprintf("PID eq %d", pid), "/FO", "CSV", "/NH")
+func getProcessNameWindows(ctx context.Context, pid int) string {
+	cmd := exec.CommandContext(ctx, "tasklist", "/FI", fmt.Sprintf("PID eq %d", pid), "/FO", "CSV", "/NH")
 	output, err := cmd.Output()
 	if err != nil {
 		return "unknown"
@@ -212,6 +477,52 @@ func FindByPort(ports []Port, portNum int) *Port {
 	return nil
 }
 
+// OlderThan keeps the ports whose owning process has been running for at
+// least age; ports whose start time is unknown are dropped
+func OlderThan(ports []Port, age time.Duration) []Port {
+	var old []Port
+	for _, p := range ports {
+		if !p.StartTime.IsZero() && p.Uptime() >= age {
+			old = append(old, p)
+		}
+	}
+	return old
+}
+
+// Protocols lists the values accepted by FilterProtocol
+var Protocols = []string{"tcp", "udp", "all"}
+
+// FilterProtocol keeps the ports of one protocol, or all of them for "all"
+func FilterProtocol(ports []Port, protocol string) ([]Port, error) {
+	switch protocol {
+	case "all", "":
+		return ports, nil
+	case "tcp", "udp":
+	default:
+		return nil, fmt.Errorf("unknown protocol %q (expected one of %s)", protocol, strings.Join(Protocols, ", "))
+	}
+
+	var matched []Port
+	for _, p := range ports {
+		if p.Protocol == protocol {
+			matched = append(matched, p)
+		}
+	}
+	return matched, nil
+}
+
+// InProject keeps the ports whose owning process runs from a checkout
+// matching filter by name, repository or path
+func InProject(ports []Port, filter string) []Port {
+	var matched []Port
+	for _, p := range ports {
+		if p.Project != nil && p.Project.Matches(filter) {
+			matched = append(matched, p)
+		}
+	}
+	return matched
+}
+
 // FindAllByPort finds all processes using a specific port
 func FindAllByPort(ports []Port, portNum int) []Port {
 	var matches []Port
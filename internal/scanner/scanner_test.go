@@ -0,0 +1,144 @@
+package scanner
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/NoaTamburrini/portman/internal/container"
+)
+
+// readFixture reads a file from testdata
+func readFixture(t *testing.T, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// summarize reduces ports to sorted "protocol port pid state" strings
+func summarize(ports []Port) []string {
+	rows := make([]string, len(ports))
+	for i, p := range ports {
+		rows[i] = fmt.Sprintf("%s %d %d %s", p.Protocol, p.Number, p.PID, p.State)
+	}
+	sort.Strings(rows)
+	return rows
+}
+
+func TestParseUnixOutputUDP(t *testing.T) {
+	ports, err := parseUnixOutput(readFixture(t, "lsof/udp.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"tcp 3000 8123 LISTEN",
+		"udp 21027 9200 UNCONN",
+		"udp 53 9001 UNCONN",
+		"udp 5353 412 UNCONN", // IPv4 and IPv6 sockets of one process
+	}
+	if got := summarize(ports); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseUnixOutput() = %q, want %q", got, want)
+	}
+}
+
+func TestParseSSOutputUDP(t *testing.T) {
+	ports := parseSSOutput(readFixture(t, "ss/udp.txt"))
+
+	want := []string{
+		"tcp 3000 8123 LISTEN",
+		"udp 41234 9100 ESTABLISHED",
+		"udp 4242 9300 UNCONN", // a socket shared by two processes
+		"udp 4242 9301 UNCONN",
+		"udp 53 611 UNCONN",
+		"udp 5353 702 UNCONN",
+	}
+	if got := summarize(ports); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseSSOutput() = %q, want %q", got, want)
+	}
+
+	for _, p := range ports {
+		if p.PID == 611 && p.ProcessName != "systemd-resolve" {
+			t.Errorf("ProcessName = %q, want systemd-resolve", p.ProcessName)
+		}
+	}
+}
+
+func TestParseWindowsOutputUDP(t *testing.T) {
+	ports, err := parseWindowsOutput(readFixture(t, "netstat/udp.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"tcp 135 1024 LISTEN",
+		"tcp 3000 8123 LISTEN",
+		"tcp 445 4 LISTEN",
+		"udp 49664 3600 ESTABLISHED",
+		"udp 5353 2280 UNCONN",
+	}
+	if got := summarize(ports); !reflect.DeepEqual(got, want) {
+		t.Errorf("parseWindowsOutput() = %q, want %q", got, want)
+	}
+}
+
+func TestFilterProtocol(t *testing.T) {
+	ports := []Port{
+		{Number: 53, Protocol: "udp"},
+		{Number: 80, Protocol: "tcp"},
+	}
+
+	for protocol, want := range map[string]int{"tcp": 1, "udp": 1, "all": 2} {
+		got, err := FilterProtocol(ports, protocol)
+		if err != nil || len(got) != want {
+			t.Errorf("FilterProtocol(%q) = %v, %v; want %d ports", protocol, got, err, want)
+		}
+	}
+
+	if _, err := FilterProtocol(ports, "sctp"); err == nil {
+		t.Error("FilterProtocol() accepted an unknown protocol")
+	}
+}
+
+func TestTagContainers(t *testing.T) {
+	web := container.Container{ID: "4f2a9c1d8e7b", Name: "web"}
+	dns := container.Container{ID: "9b8c7d6e5f4a", Name: "dns"}
+	published := map[container.Key]container.Container{
+		{Port: 8080, Protocol: "tcp", IP: "*"}:         web,
+		{Port: 5353, Protocol: "udp", IP: "127.0.0.1"}: dns,
+	}
+
+	ports := []Port{
+		{Number: 8080, Protocol: "tcp", Address: "*", ProcessName: "docker-proxy"},
+		// lsof's truncated name
+		{Number: 8080, Protocol: "tcp", Address: "*", ProcessName: "docker-pr"},
+		{Number: 5353, Protocol: "udp", Address: "127.0.0.1", ProcessName: "rootlessport"},
+		// Same port, but not the runtime's proxy
+		{Number: 8080, Protocol: "tcp", Address: "*", ProcessName: "node"},
+		{Number: 8080, Protocol: "tcp", Address: "*", ProcessName: "docker"},
+		// The proxy's port, but another address or protocol
+		{Number: 5353, Protocol: "udp", Address: "192.168.1.42", ProcessName: "rootlessport"},
+		{Number: 5353, Protocol: "udp", Address: "*", ProcessName: "rootlessport"},
+		{Number: 8080, Protocol: "udp", Address: "*", ProcessName: "docker-proxy"},
+		// Inside another network namespace
+		{Number: 8080, Protocol: "tcp", Address: "*", ProcessName: "docker-proxy", Namespace: "netns:blue"},
+	}
+	tagContainers(ports, published)
+
+	want := []string{"web", "web", "dns", "", "", "", "", "", ""}
+	for i, p := range ports {
+		got := ""
+		if p.Container != nil {
+			got = p.Container.Name
+		}
+		if got != want[i] {
+			t.Errorf("%s %s:%d/%s tagged %q, want %q", p.ProcessName, p.Address, p.Number, p.Protocol, got, want[i])
+		}
+	}
+}
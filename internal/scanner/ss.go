@@ -0,0 +1,99 @@
+package scanner
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// scanPortsSS uses ss from iproute2 to scan ports on Linux
+func scanPortsSS(ctx context.Context, t *Timing) ([]Port, []tcpSocket, error) {
+	output, err := runBackend(ctx, t, "ss", "-tuanp")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	start := time.Now()
+	ports := parseSSOutput(string(output))
+	t.Parse += time.Since(start)
+
+	// ss truncates process names to 15 characters and has no command line
+	start = time.Now()
+	defer func() { t.Enrich += time.Since(start) }()
+	for i := range ports {
+		if name, command := procName(ports[i].PID); name != "unknown" {
+			ports[i].ProcessName, ports[i].Command = name, command
+		}
+	}
+	// ss only runs on Linux, where connections come from /proc
+	return ports, nil, nil
+}
+
+// ssUsers matches one ("name",pid=N,fd=N) entry of the ss process column
+var ssUsers = regexp.MustCompile(`\("([^"]*)",pid=(\d+)`)
+
+// ssStates maps ss state names to the lsof names used in Port.State
+var ssStates = map[string]string{
+	"ESTAB":      StateEstablished,
+	"UNCONN":     StateUnconnected,
+	"TIME-WAIT":  "TIME_WAIT",
+	"CLOSE-WAIT": "CLOSE_WAIT",
+	"SYN-SENT":   "SYN_SENT",
+	"SYN-RECV":   "SYN_RECV",
+	"FIN-WAIT-1": "FIN_WAIT1",
+	"FIN-WAIT-2": "FIN_WAIT2",
+	"LAST-ACK":   "LAST_ACK",
+}
+
+// parseSSOutput parses `ss -tuanp` output, whose rows are
+// "Netid State Recv-Q Send-Q Local:Port Peer:Port [users:((...))]".
+// Sockets of other users' processes have no process column without root
+// and are skipped, as lsof skips them. Process names are left for the
+// caller to fill in from /proc.
+func parseSSOutput(output string) []Port {
+	portMap := make(map[string]Port)
+
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 7 || fields[0] == "Netid" {
+			continue
+		}
+
+		protocol := fields[0]
+		if protocol != "tcp" && protocol != "udp" {
+			continue
+		}
+
+		// Local addresses look like 0.0.0.0:68, [::]:22, *:5353 or 127.0.0.53%lo:53
+		port, ok := localPort(fields[4])
+		if !ok {
+			continue
+		}
+
+		state := fields[1]
+		if name, ok := ssStates[state]; ok {
+			state = name
+		}
+
+		// A socket shared by several processes lists each of them
+		for _, m := range ssUsers.FindAllStringSubmatch(strings.Join(fields[6:], " "), -1) {
+			pid, err := strconv.Atoi(m[2])
+			if err != nil || pid <= 0 {
+				continue
+			}
+			addPort(portMap, Port{
+				Number:      port,
+				PID:         pid,
+				ProcessName: m[1],
+				Command:     m[1],
+				Protocol:    protocol,
+				Address:     localHost(fields[4]),
+				State:       state,
+			})
+		}
+	}
+
+	return portList(portMap)
+}
@@ -0,0 +1,39 @@
+package scanner
+
+import (
+	"context"
+	"time"
+
+	"github.com/NoaTamburrini/portman/internal/process"
+)
+
+// UsageSampleInterval is how long AddUsage callers wait between two samples
+// when they need CPU usage from a single scan
+const UsageSampleInterval = 500 * time.Millisecond
+
+// sampler keeps CPU samples between scans, so CPU usage covers the time
+// since the previous refresh
+var sampler = process.NewSampler()
+
+// AddUsage attaches the resource usage of each port's owner. CPU usage is
+// measured since the previous call that saw the same PID.
+func AddUsage(ctx context.Context, ports []Port) {
+	usages := make(map[int]*process.Usage)
+	for i := range ports {
+		pid := ports[i].PID
+		usage, seen := usages[pid]
+		if !seen {
+			if u, err := sampler.Sample(ctx, pid); err == nil {
+				usage = &u
+			}
+			usages[pid] = usage
+		}
+		ports[i].Usage = usage
+	}
+
+	keep := make(map[int]bool, len(usages))
+	for pid := range usages {
+		keep[pid] = true
+	}
+	sampler.Forget(keep)
+}
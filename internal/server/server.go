@@ -0,0 +1,374 @@
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NoaTamburrini/portman/internal/metrics"
+	"github.com/NoaTamburrini/portman/internal/monitor"
+	"github.com/NoaTamburrini/portman/internal/process"
+	"github.com/NoaTamburrini/portman/internal/scanner"
+)
+
+// keepAlive is how often an idle event stream gets a comment line, so
+// proxies and clients don't time it out
+const keepAlive = 15 * time.Second
+
+// Options configures the API
+type Options struct {
+	// Token must be sent as "Authorization: Bearer <token>" to kill
+	// processes. Mutating endpoints are disabled when it is empty.
+	Token string
+	// Kill holds the default kill options; requests may override the
+	// signal and timeout
+	Kill process.KillOptions
+	// AllowForceProtected lets requests set force_protected to kill
+	// processes the protection policy would spare. Otherwise any token
+	// holder could bypass the policy, so such requests are refused.
+	AllowForceProtected bool
+	// Monitor feeds the /events stream
+	Monitor *monitor.Monitor
+	// Hosts are the names, besides localhost and IP addresses, that
+	// requests may address the server by
+	Hosts []string
+	// AuditLog is the kill log that /metrics counts kills from; empty
+	// leaves kills out of the metrics
+	AuditLog string
+}
+
+// server serves the portman HTTP API
+type server struct {
+	opts Options
+}
+
+// New returns the HTTP handler for the portman API:
+//
+//	GET  /ports             all ports, optionally ?proto=tcp|udp
+//	GET  /ports/{port}      the processes on one port
+//	POST /ports/{port}/kill kill the processes on a port
+//	GET  /events            server-sent events for ports opening and closing
+//	GET  /metrics           Prometheus metrics, as served by `portman exporter`
+func New(opts Options) http.Handler {
+	s := &server{opts: opts}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /ports", s.listPorts)
+	mux.HandleFunc("GET /ports/{port}", s.getPort)
+	mux.HandleFunc("POST /ports/{port}/kill", s.requireToken(s.killPort))
+	mux.HandleFunc("GET /events", s.events)
+	exporter := metrics.New()
+	exporter.AuditLog = opts.AuditLog
+	mux.Handle("GET /metrics", exporter)
+	return CheckHost(mux, opts.Hosts...)
+}
+
+// CheckHost rejects requests whose Host header is anything but an IP
+// address, localhost or one of hosts. A browser sends the name it looked
+// up, so a web page that rebinds its own DNS name to this machine can't
+// read the API through the browser.
+func CheckHost(next http.Handler, hosts ...string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !allowedHost(r.Host, hosts) {
+			writeError(w, http.StatusForbidden, "host %q is not allowed", r.Host)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// allowedHost reports whether a Host header names this server
+func allowedHost(header string, hosts []string) bool {
+	host := header
+	if h, _, err := net.SplitHostPort(header); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(strings.ToLower(strings.Trim(host, "[]")), ".")
+
+	// Clients that send no Host aren't browsers
+	if host == "" || host == "localhost" || net.ParseIP(host) != nil {
+		return true
+	}
+	for _, h := range hosts {
+		if strings.EqualFold(host, strings.TrimSuffix(h, ".")) {
+			return true
+		}
+	}
+	return false
+}
+
+// writeJSON writes a JSON response
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+// writeError writes a JSON error response
+func writeError(w http.ResponseWriter, status int, format string, args ...any) {
+	writeJSON(w, status, map[string]string{"error": fmt.Sprintf(format, args...)})
+}
+
+// requireToken rejects requests without the configured bearer token
+func (s *server) requireToken(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.opts.Token == "" {
+			writeError(w, http.StatusForbidden, "no token configured; mutating endpoints are disabled")
+			return
+		}
+
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.opts.Token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="portman"`)
+			writeError(w, http.StatusUnauthorized, "missing or invalid token")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// portParam parses the {port} path parameter
+func portParam(r *http.Request) (int, error) {
+	port, err := strconv.Atoi(r.PathValue("port"))
+	if err != nil || port < 1 || port > 65535 {
+		return 0, fmt.Errorf("invalid port number: %s", r.PathValue("port"))
+	}
+	return port, nil
+}
+
+// scan scans ports for a request, writing an error response on failure.
+// The scan is abandoned if the client goes away.
+func scan(w http.ResponseWriter, r *http.Request) ([]scanner.Port, bool) {
+	ports, err := scanner.ScanPortsContext(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "error scanning ports: %v", err)
+		return nil, false
+	}
+	return ports, true
+}
+
+func (s *server) listPorts(w http.ResponseWriter, r *http.Request) {
+	protocol := r.URL.Query().Get("proto")
+	if protocol == "" {
+		protocol = "all"
+	}
+	if _, err := scanner.FilterProtocol(nil, protocol); err != nil {
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+
+	ports, ok := scan(w, r)
+	if !ok {
+		return
+	}
+	ports, _ = scanner.FilterProtocol(ports, protocol)
+	writeJSON(w, http.StatusOK, ports)
+}
+
+func (s *server) getPort(w http.ResponseWriter, r *http.Request) {
+	portNum, err := portParam(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+
+	ports, ok := scan(w, r)
+	if !ok {
+		return
+	}
+
+	matches := scanner.FindAllByPort(ports, portNum)
+	if len(matches) == 0 {
+		writeError(w, http.StatusNotFound, "no process found on port %d", portNum)
+		return
+	}
+	writeJSON(w, http.StatusOK, matches)
+}
+
+// killRequest is the optional body of POST /ports/{port}/kill
+type killRequest struct {
+	// PID limits the kill to one of the processes on the port
+	PID            int    `json:"pid"`
+	Signal         string `json:"signal"`
+	Timeout        string `json:"timeout"`
+	ForceProtected bool   `json:"force_protected"`
+}
+
+// killResult reports the kill of one process
+type killResult struct {
+	PID       int    `json:"pid"`
+	Process   string `json:"process"`
+	Success   bool   `json:"success"`
+	Protected bool   `json:"protected,omitempty"`
+	// Container is set when the port was left for its container to be stopped
+	Container bool   `json:"container,omitempty"`
+	Forced    bool   `json:"forced,omitempty"`
+	Message   string `json:"message"`
+}
+
+// killOptions applies a request's overrides to the default kill options
+func (s *server) killOptions(req killRequest, port int) (process.KillOptions, error) {
+	opts := s.opts.Kill
+	opts.Port = port
+	opts.ForceProtected = req.ForceProtected
+
+	if req.Signal != "" {
+		sig, err := process.ParseSignal(req.Signal)
+		if err != nil {
+			return opts, err
+		}
+		opts.Signal = sig
+	}
+	if req.Timeout != "" {
+		timeout, err := time.ParseDuration(req.Timeout)
+		if err != nil {
+			return opts, fmt.Errorf("invalid timeout %q", req.Timeout)
+		}
+		opts.Timeout = timeout
+	}
+	return opts, nil
+}
+
+func (s *server) killPort(w http.ResponseWriter, r *http.Request) {
+	portNum, err := portParam(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+
+	var req killRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid request body: %v", err)
+			return
+		}
+	}
+
+	if req.ForceProtected && !s.opts.AllowForceProtected {
+		writeError(w, http.StatusForbidden, "force_protected is not allowed; start portman serve with --allow-force-protected to allow it")
+		return
+	}
+
+	opts, err := s.killOptions(req, portNum)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "%v", err)
+		return
+	}
+
+	ports, ok := scan(w, r)
+	if !ok {
+		return
+	}
+
+	var matches []scanner.Port
+	for _, p := range scanner.FindAllByPort(ports, portNum) {
+		if req.PID == 0 || p.PID == req.PID {
+			matches = append(matches, p)
+		}
+	}
+	if len(matches) == 0 {
+		writeError(w, http.StatusNotFound, "no process found on port %d", portNum)
+		return
+	}
+
+	results := make([]killResult, 0, len(matches))
+	success := true
+	for _, p := range matches {
+		result := killResult{PID: p.PID, Process: p.ProcessName}
+
+		// Killing a container's proxy breaks the runtime instead of
+		// stopping the container
+		if c := p.Container; c != nil {
+			result.Container = true
+			result.Message = fmt.Sprintf("Port %d is published by %s container %s; stop the container instead", p.Number, c.Runtime, c.Name)
+		} else {
+			killed := process.KillProcessContext(r.Context(), p.PID, opts)
+			result.Success, result.Protected, result.Forced = killed.Success, killed.Protected, killed.Forced
+			result.Message = killed.Message
+		}
+
+		success = success && result.Success
+		results = append(results, result)
+	}
+
+	writeJSON(w, killStatus(results), map[string]any{
+		"success": success,
+		"results": results,
+	})
+}
+
+// killStatus picks the response status for a kill: 200 when every process
+// was killed, 500 when a kill failed, 403 when protection refused one and
+// 409 when a port was left for its container to be stopped
+func killStatus(results []killResult) int {
+	status := http.StatusOK
+	for _, r := range results {
+		switch {
+		case r.Success:
+		case r.Protected:
+			status = http.StatusForbidden
+		case r.Container:
+			if status == http.StatusOK {
+				status = http.StatusConflict
+			}
+		default:
+			return http.StatusInternalServerError
+		}
+	}
+	return status
+}
+
+// events streams port changes as server-sent events until the client
+// disconnects. Each event is named "open" or "close" and carries a
+// monitor.Event as JSON.
+func (s *server) events(w http.ResponseWriter, r *http.Request) {
+	if s.opts.Monitor == nil {
+		writeError(w, http.StatusNotImplemented, "events are not enabled")
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming is not supported")
+		return
+	}
+
+	events, unsubscribe := s.opts.Monitor.Subscribe()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, ": portman events\n\n")
+	flusher.Flush()
+
+	ticker := time.NewTicker(keepAlive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+		case e, ok := <-events:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(e)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, data)
+		}
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,124 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestKillRequiresToken(t *testing.T) {
+	tests := []struct {
+		name   string
+		token  string
+		header string
+		want   int
+	}{
+		{"no token configured", "", "Bearer secret", http.StatusForbidden},
+		{"missing header", "secret", "", http.StatusUnauthorized},
+		{"wrong token", "secret", "Bearer nope", http.StatusUnauthorized},
+		{"not a bearer token", "secret", "secret", http.StatusUnauthorized},
+		// Authorized requests get as far as validating the port
+		{"valid token", "secret", "Bearer secret", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := New(Options{Token: tt.token})
+			req := httptest.NewRequest(http.MethodPost, "http://localhost:7070/ports/0/kill", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+		})
+	}
+}
+
+func TestForceProtectedNeedsOptIn(t *testing.T) {
+	handler := New(Options{Token: "secret"})
+	body := strings.NewReader(`{"force_protected": true}`)
+	req := httptest.NewRequest(http.MethodPost, "http://localhost:7070/ports/3000/kill", body)
+	req.Header.Set("Authorization", "Bearer secret")
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("status = %d, want %d: %s", rec.Code, http.StatusForbidden, rec.Body)
+	}
+}
+
+func TestKillOptions(t *testing.T) {
+	s := &server{}
+
+	opts, err := s.killOptions(killRequest{Signal: "INT", Timeout: "5s", ForceProtected: true}, 3000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opts.Port != 3000 || opts.Signal.String() != "interrupt" || opts.Timeout.String() != "5s" || !opts.ForceProtected {
+		t.Errorf("killOptions() = %+v", opts)
+	}
+
+	for _, req := range []killRequest{{Signal: "NOPE"}, {Timeout: "soon"}} {
+		if _, err := s.killOptions(req, 3000); err == nil {
+			t.Errorf("killOptions(%+v) accepted an invalid request", req)
+		}
+	}
+}
+
+func TestKillStatus(t *testing.T) {
+	killed := killResult{Success: true}
+	protected := killResult{Protected: true}
+	container := killResult{Container: true}
+	failed := killResult{Message: "operation not permitted"}
+
+	tests := []struct {
+		name    string
+		results []killResult
+		want    int
+	}{
+		{"all killed", []killResult{killed, killed}, http.StatusOK},
+		{"protected", []killResult{killed, protected}, http.StatusForbidden},
+		{"container", []killResult{container}, http.StatusConflict},
+		{"protected beats container", []killResult{container, protected}, http.StatusForbidden},
+		{"failed beats protected", []killResult{protected, failed, killed}, http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		if got := killStatus(tt.results); got != tt.want {
+			t.Errorf("%s: killStatus() = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCheckHost(t *testing.T) {
+	tests := []struct {
+		host string
+		want int
+	}{
+		{"localhost:7070", http.StatusOK},
+		{"127.0.0.1:7070", http.StatusOK},
+		{"[::1]:7070", http.StatusOK},
+		{"devbox.internal:7070", http.StatusOK},
+		{"DEVBOX.internal.", http.StatusOK},
+		// A rebound DNS name pointing at this machine
+		{"attacker.example:7070", http.StatusForbidden},
+		{"localhost.attacker.example", http.StatusForbidden},
+	}
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	handler := CheckHost(ok, "devbox.internal")
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/ports", nil)
+		req.Host = tt.host
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("Host %q: status = %d, want %d", tt.host, rec.Code, tt.want)
+		}
+	}
+}
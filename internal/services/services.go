@@ -0,0 +1,158 @@
+package services
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// EtcServicesPath is the system services database read by Configure
+const EtcServicesPath = "/etc/services"
+
+// builtin maps well-known ports to service names, favouring the services a
+// developer machine is likely to run over what IANA assigned
+var builtin = map[int]string{
+	21:    "ftp",
+	22:    "ssh",
+	23:    "telnet",
+	25:    "smtp",
+	53:    "dns",
+	80:    "http",
+	110:   "pop3",
+	143:   "imap",
+	443:   "https",
+	465:   "smtps",
+	587:   "submission",
+	631:   "cups",
+	993:   "imaps",
+	995:   "pop3s",
+	1433:  "mssql",
+	1521:  "oracle",
+	1883:  "mqtt",
+	2049:  "nfs",
+	2181:  "zookeeper",
+	2375:  "docker",
+	2376:  "docker-tls",
+	2379:  "etcd",
+	3100:  "loki",
+	3306:  "mysql",
+	3389:  "rdp",
+	4200:  "angular",
+	4222:  "nats",
+	4317:  "otlp-grpc",
+	4318:  "otlp-http",
+	5173:  "vite",
+	5432:  "postgres",
+	5601:  "kibana",
+	5672:  "rabbitmq",
+	5900:  "vnc",
+	6006:  "storybook",
+	6379:  "redis",
+	6443:  "kube-apiserver",
+	8080:  "http-alt",
+	8086:  "influxdb",
+	8443:  "https-alt",
+	8500:  "consul",
+	8888:  "jupyter",
+	8983:  "solr",
+	9090:  "prometheus",
+	9092:  "kafka",
+	9093:  "alertmanager",
+	9100:  "node-exporter",
+	9200:  "elasticsearch",
+	9229:  "node-inspector",
+	9300:  "elasticsearch-transport",
+	11211: "memcached",
+	15672: "rabbitmq-management",
+	16686: "jaeger",
+	24678: "vite-hmr",
+	27017: "mongodb",
+}
+
+var (
+	byPort = make(map[int]string)
+	byName = make(map[string]int)
+)
+
+func init() {
+	Configure(nil, "")
+}
+
+// Configure rebuilds the service table from the built-in ports, extra
+// names from config, and optionally a services database such as
+// /etc/services. Config overrides the built-in table, which overrides the
+// database. A missing database file is ignored.
+func Configure(extra map[string]int, etcServices string) {
+	byPort = make(map[int]string)
+	byName = make(map[string]int)
+
+	if etcServices != "" {
+		if data, err := os.ReadFile(etcServices); err == nil {
+			ports, names := parseEtcServices(string(data))
+			for port, name := range ports {
+				byPort[port] = name
+			}
+			for name, port := range names {
+				byName[name] = port
+			}
+		}
+	}
+
+	for port, name := range builtin {
+		add(name, port)
+	}
+	for name, port := range extra {
+		add(name, port)
+	}
+}
+
+// add records a service name for a port, replacing any earlier one
+func add(name string, port int) {
+	byPort[port] = name
+	byName[strings.ToLower(name)] = port
+}
+
+// Name returns the well-known service name for a port, or "" if none
+func Name(port int) string {
+	return byPort[port]
+}
+
+// Port resolves a well-known service name, case-insensitively
+func Port(name string) (int, bool) {
+	port, ok := byName[strings.ToLower(name)]
+	return port, ok
+}
+
+// parseEtcServices parses the services database format, one
+// "name port/protocol [aliases...] [# comment]" per line. Each port is
+// named after its first entry; the name and aliases all resolve to it.
+func parseEtcServices(data string) (map[int]string, map[string]int) {
+	ports := make(map[int]string)
+	names := make(map[string]int)
+
+	for _, line := range strings.Split(data, "\n") {
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+
+		number, _, _ := strings.Cut(fields[1], "/")
+		port, err := strconv.Atoi(number)
+		if err != nil || port < 1 || port > 65535 {
+			continue
+		}
+
+		if _, ok := ports[port]; !ok {
+			ports[port] = fields[0]
+		}
+		for _, name := range append([]string{fields[0]}, fields[2:]...) {
+			if _, ok := names[strings.ToLower(name)]; !ok {
+				names[strings.ToLower(name)] = port
+			}
+		}
+	}
+	return ports, names
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const etcServices = `# Network services, Internet style
+tcpmux		1/tcp				# TCP port service multiplexer
+ssh		22/tcp				# SSH Remote Login Protocol
+domain		53/tcp
+domain		53/udp
+postgresql	5432/tcp	postgres	# PostgreSQL Database
+x11		6000/tcp	x11-0		# X Window System
+bogus		99999/tcp
+`
+
+func TestParseEtcServices(t *testing.T) {
+	ports, names := parseEtcServices(etcServices)
+
+	if ports[53] != "domain" || ports[6000] != "x11" || ports[5432] != "postgresql" {
+		t.Errorf("ports = %v", ports)
+	}
+	if _, ok := ports[99999]; ok {
+		t.Error("parseEtcServices() kept an out of range port")
+	}
+	if names["x11-0"] != 6000 || names["postgres"] != 5432 || names["tcpmux"] != 1 {
+		t.Errorf("names = %v", names)
+	}
+}
+
+func TestConfigure(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "services")
+	if err := os.WriteFile(path, []byte(etcServices), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	defer Configure(nil, "")
+
+	Configure(map[string]int{"Grafana": 3000, "db": 5432}, path)
+
+	tests := []struct {
+		port int
+		name string
+	}{
+		{3000, "Grafana"}, // from config
+		{5432, "db"},      // config overrides the built-in postgres
+		{6379, "redis"},   // built-in
+		{6000, "x11"},     // from /etc/services
+		{1, "tcpmux"},     // from /etc/services
+	}
+	for _, tt := range tests {
+		if got := Name(tt.port); got != tt.name {
+			t.Errorf("Name(%d) = %q, want %q", tt.port, got, tt.name)
+		}
+	}
+
+	for name, want := range map[string]int{"grafana": 3000, "postgres": 5432, "DB": 5432, "x11-0": 6000, "vite": 5173} {
+		if got, ok := Port(name); !ok || got != want {
+			t.Errorf("Port(%q) = %d, %v; want %d", name, got, ok, want)
+		}
+	}
+
+	Configure(nil, "")
+	if _, ok := Port("x11"); ok {
+		t.Error("Port() still resolves /etc/services names after reconfiguring without it")
+	}
+}
@@ -0,0 +1,145 @@
+package systemd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// Unit is a systemd unit supervising a process or holding a socket
+type Unit struct {
+	Name string `json:"name"`
+	// User is set for units of a user manager (systemctl --user)
+	User bool `json:"user,omitempty"`
+}
+
+// StopCommand returns the systemctl command line that stops the unit
+func (u Unit) StopCommand() string {
+	if u.User {
+		return "systemctl --user stop " + u.Name
+	}
+	return "systemctl stop " + u.Name
+}
+
+// Restarts reports whether killing the unit's process is likely to be
+// undone by systemd, i.e. the process belongs to a service or socket
+func (u Unit) Restarts() bool {
+	return strings.HasSuffix(u.Name, ".service") || strings.HasSuffix(u.Name, ".socket")
+}
+
+// UnitForPID resolves the systemd unit a process belongs to from its cgroup
+func UnitForPID(pid int) (Unit, bool) {
+	if runtime.GOOS != "linux" {
+		return Unit{}, false
+	}
+
+	data, err := os.ReadFile(fmt.Sprintf("/proc/%d/cgroup", pid))
+	if err != nil {
+		return Unit{}, false
+	}
+	return parseCgroup(string(data))
+}
+
+// parseCgroup finds the unit in /proc/<pid>/cgroup. On cgroup v2 the only
+// line is "0::<path>"; on v1 the "name=systemd" hierarchy carries the path.
+func parseCgroup(data string) (Unit, bool) {
+	var path string
+	for _, line := range strings.Split(strings.TrimSpace(data), "\n") {
+		parts := strings.SplitN(line, ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		if (parts[0] == "0" && parts[1] == "") || parts[1] == "name=systemd" {
+			path = parts[2]
+			break
+		}
+	}
+	if path == "" {
+		return Unit{}, false
+	}
+
+	// The innermost service or scope is the unit; anything below
+	// user@<uid>.service is managed by the user's own systemd
+	var unit Unit
+	user := false
+	for _, component := range strings.Split(path, "/") {
+		if strings.HasPrefix(component, "user@") && strings.HasSuffix(component, ".service") {
+			user = true
+			continue
+		}
+		if strings.HasSuffix(component, ".service") || strings.HasSuffix(component, ".scope") {
+			unit = Unit{Name: component, User: user}
+		}
+	}
+
+	return unit, unit.Name != ""
+}
+
+// SocketUnits maps ports to the .socket units listening on them, for
+// socket-activated services whose port is held by systemd itself
+func SocketUnits(ctx context.Context) map[int]Unit {
+	units := make(map[int]Unit)
+	if runtime.GOOS != "linux" {
+		return units
+	}
+
+	for _, user := range []bool{false, true} {
+		args := []string{"list-sockets", "--all", "--no-legend", "--no-pager"}
+		if user {
+			args = append([]string{"--user"}, args...)
+		}
+
+		output, err := exec.CommandContext(ctx, "systemctl", args...).Output()
+		if err != nil {
+			continue
+		}
+		for port, name := range parseListSockets(string(output)) {
+			units[port] = Unit{Name: name, User: user}
+		}
+	}
+	return units
+}
+
+// parseListSockets parses `systemctl list-sockets --no-legend` output,
+// whose lines are "LISTEN UNIT ACTIVATES", keeping network listeners
+func parseListSockets(output string) map[int]string {
+	sockets := make(map[int]string)
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "/") || strings.HasPrefix(fields[0], "@") {
+			continue
+		}
+
+		listen := fields[0]
+		i := strings.LastIndex(listen, ":")
+		if i < 0 {
+			continue
+		}
+		if port, err := strconv.Atoi(listen[i+1:]); err == nil {
+			sockets[port] = fields[1]
+		}
+	}
+	return sockets
+}
+
+// Stop stops a unit with systemctl, failing rather than prompting when
+// authentication is needed
+func Stop(ctx context.Context, u Unit) error {
+	args := []string{"--no-ask-password", "stop", u.Name}
+	if u.User {
+		args = append([]string{"--user"}, args...)
+	}
+
+	output, err := exec.CommandContext(ctx, "systemctl", args...).CombinedOutput()
+	if err != nil {
+		if msg := strings.TrimSpace(string(output)); msg != "" {
+			return fmt.Errorf("%s failed: %s", u.StopCommand(), msg)
+		}
+		return fmt.Errorf("%s failed: %w", u.StopCommand(), err)
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package systemd
+
+import "testing"
+
+func TestParseCgroup(t *testing.T) {
+	tests := []struct {
+		name   string
+		cgroup string
+		want   Unit
+		ok     bool
+	}{
+		{"system service v2", "0::/system.slice/nginx.service\n", Unit{Name: "nginx.service"}, true},
+		{"user service v2", "0::/user.slice/user-1000.slice/user@1000.service/app.slice/vite.service\n", Unit{Name: "vite.service", User: true}, true},
+		{"login session", "0::/user.slice/user-1000.slice/session-3.scope\n", Unit{Name: "session-3.scope"}, true},
+		{"hybrid v1", "12:pids:/system.slice/redis.service\n1:name=systemd:/system.slice/redis.service\n0::/system.slice/redis.service\n", Unit{Name: "redis.service"}, true},
+		{"root cgroup", "0::/\n", Unit{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := parseCgroup(tt.cgroup)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("parseCgroup() = %+v, %v; want %+v, %v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestParseListSockets(t *testing.T) {
+	output := `/run/dbus/system_bus_socket dbus.socket     dbus.service
+[::]:22                     sshd.socket     sshd.service
+0.0.0.0:631                 cups.socket     cups.service
+@ISCSIADM_ABSTRACT_NAMESPACE iscsid.socket  iscsid.service
+`
+	sockets := parseListSockets(output)
+	if len(sockets) != 2 || sockets[22] != "sshd.socket" || sockets[631] != "cups.socket" {
+		t.Errorf("parseListSockets() = %v, want sshd.socket on 22 and cups.socket on 631", sockets)
+	}
+}
@@ -0,0 +1,89 @@
+package tui
+
+import (
+	"strings"
+
+	"github.com/NoaTamburrini/portman/internal/config"
+)
+
+// keyMap holds the keys bound to each TUI action
+type keyMap struct {
+	up      []string
+	down    []string
+	kill    []string
+	refresh []string
+	filter  []string
+	details []string
+	usage   []string
+	sort    []string
+	// connections lists the clients connected to the selected port
+	connections []string
+	// killSupervisor kills the process manager that restarted a killed process
+	killSupervisor []string
+	// relaunch starts the last killed process again
+	relaunch []string
+	pause    []string
+	resume   []string
+	// cancel stops the scans and kills in flight
+	cancel []string
+	quit   []string
+}
+
+// newKeyMap builds the key map from config, keeping defaults for unset actions
+func newKeyMap(bindings config.KeyBindings) keyMap {
+	defaults := config.Default().TUI.Keys
+	pick := func(keys, fallback []string) []string {
+		if len(keys) == 0 {
+			return fallback
+		}
+		return keys
+	}
+
+	return keyMap{
+		up:             pick(bindings.Up, defaults.Up),
+		down:           pick(bindings.Down, defaults.Down),
+		kill:           pick(bindings.Kill, defaults.Kill),
+		refresh:        pick(bindings.Refresh, defaults.Refresh),
+		filter:         pick(bindings.Filter, defaults.Filter),
+		details:        pick(bindings.Details, defaults.Details),
+		connections:    pick(bindings.Connections, defaults.Connections),
+		usage:          pick(bindings.Usage, defaults.Usage),
+		sort:           pick(bindings.Sort, defaults.Sort),
+		killSupervisor: pick(bindings.KillSupervisor, defaults.KillSupervisor),
+		relaunch:       pick(bindings.Relaunch, defaults.Relaunch),
+		pause:          pick(bindings.Pause, defaults.Pause),
+		resume:         pick(bindings.Resume, defaults.Resume),
+		cancel:         pick(bindings.Cancel, defaults.Cancel),
+		quit:           pick(bindings.Quit, defaults.Quit),
+	}
+}
+
+// matches reports whether key is one of the bound keys
+func matches(key string, bound []string) bool {
+	for _, k := range bound {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
+
+// helpKey formats the keys bound to an action for the help line
+func helpKey(bound []string) string {
+	names := make([]string, len(bound))
+	for i, k := range bound {
+		switch k {
+		case "up":
+			names[i] = "↑"
+		case "down":
+			names[i] = "↓"
+		case "enter":
+			names[i] = "Enter"
+		case "esc":
+			names[i] = "Esc"
+		default:
+			names[i] = k
+		}
+	}
+	return strings.Join(names, "/")
+}
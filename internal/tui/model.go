@@ -1,11 +1,20 @@
 package tui
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"sort"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/NoaTamburrini/portman/internal/config"
+	"github.com/NoaTamburrini/portman/internal/monitor"
+	"github.com/NoaTamburrini/portman/internal/process"
+	"github.com/NoaTamburrini/portman/internal/project"
 	"github.com/NoaTamburrini/portman/internal/scanner"
+	"github.com/NoaTamburrini/portman/internal/services"
 
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
@@ -21,21 +30,140 @@ type Model struct {
 	filterMode     bool
 	filterInput    textinput.Model
 	confirmingKill bool
-	width          int
-	height         int
+	confirmAction  action
+	confirmPort    scanner.Port // what the prompt acts on, fixed when it opens
+	showDetails    bool
+	showUsage      bool
+	sortBy         sortMode
+	killOptions    process.KillOptions
+	respawnWindow  time.Duration
+	lastKilled     *scanner.Port
+	killedAt       time.Time
+	respawn        *scanner.Respawn
+	// relaunch is how the last killed process was started, for R
+	relaunch        *process.Launch
+	relaunchPort    int
+	refreshInterval time.Duration
+	scanTimeout     time.Duration
+	keys            keyMap
+	project         *project.Project
+	protocol        string
+	width           int
+	height          int
+	// showConnections lists the selected port's client connections
+	showConnections bool
+	// events reports ports opening and closing, when a monitor runs;
+	// eventPending is set while taking its ports waits for a quiet moment
+	monitor      *monitor.Monitor
+	events       <-chan monitor.Event
+	eventPending bool
+	// ops tracks the scans and kills in flight, for Esc to cancel
+	ops *operations
 }
 
+// operations hands out the context that scans and kills run under. It is
+// shared by every copy of the Model, since commands run on their own
+// goroutines after Update has returned.
+type operations struct {
+	mu       sync.Mutex
+	ctx      context.Context
+	cancel   context.CancelFunc
+	inFlight int
+}
+
+// newOperations creates a tracker with nothing in flight
+func newOperations() *operations {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &operations{ctx: ctx, cancel: cancel}
+}
+
+// begin starts an operation, returning its context and a function to
+// call when it finishes
+func (o *operations) begin() (context.Context, func()) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.inFlight++
+	return o.ctx, func() {
+		o.mu.Lock()
+		defer o.mu.Unlock()
+		o.inFlight--
+	}
+}
+
+// cancelAll cancels the operations in flight, leaving a fresh context for
+// the ones that follow, and reports whether there were any
+func (o *operations) cancelAll() bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.inFlight == 0 {
+		return false
+	}
+	o.cancel()
+	o.ctx, o.cancel = context.WithCancel(context.Background())
+	return true
+}
+
+// action is what confirming a kill prompt does
+type action int
+
+const (
+	actionKill action = iota
+	actionStopContainer
+	actionStopUnit
+	actionKillSupervisor
+)
+
+// sortMode is the order of the port list
+type sortMode int
+
+const (
+	sortByPort sortMode = iota
+	sortByUptime
+)
+
 type scanCompleteMsg struct {
 	ports []scanner.Port
 	err   error
+	// cancelled is set when Esc stopped the scan
+	cancelled bool
 }
 
+type refreshTickMsg struct{}
+
 type killCompleteMsg struct {
 	success bool
 	message string
+	// killed is the port whose process was signalled, for respawn detection
+	killed *scanner.Port
+	// launch is how the killed process was started, when it could be captured
+	launch *process.Launch
+}
+
+type relaunchCompleteMsg struct {
+	pid    int
+	port   int
+	launch process.Launch
+	err    error
+}
+
+// pauseCompleteMsg reports pausing or resuming a process
+type pauseCompleteMsg struct {
+	success bool
+	message string
+	pid     int
+	paused  bool
 }
 
-func initialModel() Model {
+// portEventMsg reports a port opening or closing. Retries are sent for
+// events that arrived while the monitor's ports couldn't be taken.
+type portEventMsg struct {
+	retry bool
+}
+
+// respawnCheckMsg triggers another scan while watching for a respawn
+type respawnCheckMsg struct{}
+
+func initialModel(opts Options) Model {
 	ti := textinput.New()
 	ti.Placeholder = "Filter ports..."
 	ti.CharLimit = 50
@@ -43,51 +171,147 @@ func initialModel() Model {
 	ti.PlaceholderStyle = placeholderStyle
 	ti.PromptStyle = filterStyle
 
+	scanTimeout := opts.ScanTimeout
+	if scanTimeout <= 0 {
+		scanTimeout = config.DefaultScanTimeout
+	}
+
+	var events <-chan monitor.Event
+	if opts.Monitor != nil {
+		// The subscription lasts as long as the TUI
+		events, _ = opts.Monitor.Subscribe()
+	}
+
 	return Model{
-		ports:         []scanner.Port{},
-		filteredPorts: []scanner.Port{},
-		cursor:        0,
-		filterInput:   ti,
+		ports:           []scanner.Port{},
+		filteredPorts:   []scanner.Port{},
+		cursor:          0,
+		filterInput:     ti,
+		killOptions:     opts.Kill,
+		respawnWindow:   opts.RespawnWindow,
+		refreshInterval: opts.RefreshInterval,
+		scanTimeout:     scanTimeout,
+		keys:            newKeyMap(opts.Keys),
+		project:         opts.Project,
+		protocol:        opts.Protocol,
+		monitor:         opts.Monitor,
+		events:          events,
+		ops:             newOperations(),
 	}
 }
 
 func (m Model) Init() tea.Cmd {
-	return scanPorts
+	return tea.Batch(m.scanPorts, m.refreshTick(), m.waitForEvent())
+}
+
+// waitForEvent waits for the monitor to report a port opening or closing
+func (m Model) waitForEvent() tea.Cmd {
+	if m.events == nil {
+		return nil
+	}
+	return func() tea.Msg {
+		if _, ok := <-m.events; !ok {
+			return nil
+		}
+		return portEventMsg{}
+	}
+}
+
+// eventRetryDelay is how long a rescan for a port event waits when one
+// can't start right away
+const eventRetryDelay = time.Second
+
+// refreshTick schedules the next automatic refresh, if enabled
+func (m Model) refreshTick() tea.Cmd {
+	if m.refreshInterval <= 0 {
+		return nil
+	}
+	return tea.Tick(m.refreshInterval, func(time.Time) tea.Msg {
+		return refreshTickMsg{}
+	})
 }
 
-// scanPorts performs a port scan
-func scanPorts() tea.Msg {
-	ports, err := scanner.ScanPorts()
+// scanPorts performs a port scan, sampling resource usage when its
+// columns are shown
+func (m Model) scanPorts() tea.Msg {
+	ctx, done := m.ops.begin()
+	defer done()
+	ctx, cancel := context.WithTimeout(ctx, m.scanTimeout)
+	defer cancel()
+
+	ports, err := scanner.ScanPortsContext(ctx)
+	if errors.Is(err, context.Canceled) {
+		return scanCompleteMsg{cancelled: true}
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return scanCompleteMsg{err: fmt.Errorf("scan timed out after %s", m.scanTimeout)}
+	}
 	if err != nil {
 		return scanCompleteMsg{ports: nil, err: err}
 	}
 
-	// Sort by port number
-	sort.Slice(ports, func(i, j int) bool {
-		return ports[i].Number < ports[j].Number
-	})
+	if m.showUsage {
+		scanner.AddUsage(ctx, ports)
+	}
 
 	return scanCompleteMsg{ports: ports, err: nil}
 }
 
+// monitorPorts takes the ports from the monitor's latest scan, the one
+// that reported a change, rather than scanning again
+func (m Model) monitorPorts() tea.Msg {
+	ports, err := m.monitor.Ports()
+	if err != nil {
+		return scanCompleteMsg{err: err}
+	}
+
+	if m.showUsage {
+		ctx, done := m.ops.begin()
+		defer done()
+		scanner.AddUsage(ctx, ports)
+	}
+
+	return scanCompleteMsg{ports: ports}
+}
+
+// sortPorts orders the ports by the current sort mode
+func (m *Model) sortPorts() {
+	sort.SliceStable(m.ports, func(i, j int) bool {
+		a, b := m.ports[i], m.ports[j]
+		if m.sortBy == sortByUptime && !a.StartTime.Equal(b.StartTime) {
+			// Oldest first, unknown start times last
+			if a.StartTime.IsZero() || b.StartTime.IsZero() {
+				return b.StartTime.IsZero()
+			}
+			return a.StartTime.Before(b.StartTime)
+		}
+		return a.Number < b.Number
+	})
+}
 
 // filterPorts filters the ports based on the filter string
 func (m *Model) filterPorts() {
 	filter := strings.ToLower(strings.TrimSpace(m.filterInput.Value()))
 
-	if filter == "" {
-		m.filteredPorts = m.ports
-		return
-	}
+	// The protocol was validated when the TUI started
+	ports, _ := scanner.FilterProtocol(m.ports, m.protocol)
 
 	filtered := []scanner.Port{}
-	for _, p := range m.ports {
-		// Check if filter matches port number, process name, or command
+	for _, p := range ports {
+		if filter == "" {
+			filtered = append(filtered, p)
+			continue
+		}
+
+		// Check if filter matches port number, service, process name, command, or project
 		portNum := fmt.Sprintf("%d", p.Number)
 		if strings.Contains(portNum, filter) ||
+			strings.Contains(strings.ToLower(m.serviceName(p.Number)), filter) ||
 			strings.Contains(strings.ToLower(p.ProcessName), filter) ||
 			strings.Contains(strings.ToLower(p.Command), filter) ||
-			strings.Contains(strings.ToLower(p.Protocol), filter) {
+			strings.Contains(strings.ToLower(p.Protocol), filter) ||
+			strings.Contains(strings.ToLower(p.Namespace), filter) ||
+			(p.Project != nil && p.Project.Matches(filter)) {
 			filtered = append(filtered, p)
 		}
 	}
@@ -100,6 +324,15 @@ func (m *Model) filterPorts() {
 	}
 }
 
+// serviceName names the service on a port: the project service declared
+// on it, or else its well-known service name
+func (m Model) serviceName(port int) string {
+	if service, ok := m.project.ServiceForPort(port); ok {
+		return service.Name
+	}
+	return services.Name(port)
+}
+
 func max(a, b int) int {
 	if a > b {
 		return a
@@ -1,64 +1,110 @@
 package tui
 
 import (
+	"github.com/NoaTamburrini/portman/internal/config"
+
 	"github.com/charmbracelet/lipgloss"
 )
 
 var (
 	// Colors
-	primaryColor   = lipgloss.Color("86")   // Cyan
-	secondaryColor = lipgloss.Color("212")  // Pink
-	successColor   = lipgloss.Color("42")   // Green
-	errorColor     = lipgloss.Color("196")  // Red
-	mutedColor     = lipgloss.Color("241")  // Gray
-	selectedColor  = lipgloss.Color("219")  // Light purple
+	primaryColor   = lipgloss.Color("86")  // Cyan
+	secondaryColor = lipgloss.Color("212") // Pink
+	successColor   = lipgloss.Color("42")  // Green
+	errorColor     = lipgloss.Color("196") // Red
+	mutedColor     = lipgloss.Color("241") // Gray
+	selectedColor  = lipgloss.Color("219") // Light purple
+	highlightColor = lipgloss.Color("235") // Dark gray
+
+	titleStyle       lipgloss.Style
+	headerStyle      lipgloss.Style
+	rowStyle         lipgloss.Style
+	selectedRowStyle lipgloss.Style
+	helpStyle        lipgloss.Style
+	successStyle     lipgloss.Style
+	errorStyle       lipgloss.Style
+	filterStyle      lipgloss.Style
+	placeholderStyle lipgloss.Style
+	detailsStyle     lipgloss.Style
+)
+
+func init() {
+	buildStyles()
+}
+
+// applyTheme overrides the default colors with any set in the theme
+func applyTheme(theme config.Theme) {
+	set := func(color *lipgloss.Color, value string) {
+		if value != "" {
+			*color = lipgloss.Color(value)
+		}
+	}
 
+	set(&primaryColor, theme.Primary)
+	set(&secondaryColor, theme.Secondary)
+	set(&successColor, theme.Success)
+	set(&errorColor, theme.Error)
+	set(&mutedColor, theme.Muted)
+	set(&selectedColor, theme.Selected)
+	set(&highlightColor, theme.Highlight)
+
+	buildStyles()
+}
+
+// buildStyles derives all styles from the current colors
+func buildStyles() {
 	// Title style
 	titleStyle = lipgloss.NewStyle().
-			Foreground(primaryColor).
-			Bold(true).
-			Padding(0, 1)
+		Foreground(primaryColor).
+		Bold(true).
+		Padding(0, 1)
 
 	// Header row style
 	headerStyle = lipgloss.NewStyle().
-			Foreground(secondaryColor).
-			Bold(true).
-			BorderStyle(lipgloss.NormalBorder()).
-			BorderBottom(true).
-			BorderForeground(mutedColor)
+		Foreground(secondaryColor).
+		Bold(true).
+		BorderStyle(lipgloss.NormalBorder()).
+		BorderBottom(true).
+		BorderForeground(mutedColor)
 
 	// Normal row style
 	rowStyle = lipgloss.NewStyle().
-			Padding(0, 1)
+		Padding(0, 1)
 
 	// Selected row style
 	selectedRowStyle = lipgloss.NewStyle().
-				Foreground(selectedColor).
-				Background(lipgloss.Color("235")).
-				Bold(true).
-				Padding(0, 1)
+		Foreground(selectedColor).
+		Background(highlightColor).
+		Bold(true).
+		Padding(0, 1)
 
 	// Help text style
 	helpStyle = lipgloss.NewStyle().
-			Foreground(mutedColor).
-			Padding(1, 0)
+		Foreground(mutedColor).
+		Padding(1, 0)
 
 	// Status message styles
 	successStyle = lipgloss.NewStyle().
-			Foreground(successColor).
-			Bold(true)
+		Foreground(successColor).
+		Bold(true)
 
 	errorStyle = lipgloss.NewStyle().
-			Foreground(errorColor).
-			Bold(true)
+		Foreground(errorColor).
+		Bold(true)
 
 	// Filter input style
 	filterStyle = lipgloss.NewStyle().
-			Foreground(primaryColor).
-			Bold(true)
+		Foreground(primaryColor).
+		Bold(true)
 
 	// Placeholder style
 	placeholderStyle = lipgloss.NewStyle().
-				Foreground(mutedColor).
-				Italic(true)
-)
+		Foreground(mutedColor).
+		Italic(true)
+
+	// Details panel style
+	detailsStyle = lipgloss.NewStyle().
+		BorderStyle(lipgloss.RoundedBorder()).
+		BorderForeground(mutedColor).
+		Padding(0, 1)
+}
@@ -3,13 +3,42 @@ package tui
 import (
 	"fmt"
 	"os"
+	"time"
+
+	"github.com/NoaTamburrini/portman/internal/config"
+	"github.com/NoaTamburrini/portman/internal/monitor"
+	"github.com/NoaTamburrini/portman/internal/process"
+	"github.com/NoaTamburrini/portman/internal/project"
 
 	tea "github.com/charmbracelet/bubbletea"
 )
 
+// Options configures the TUI
+type Options struct {
+	// Kill is used for every kill started from the TUI
+	Kill process.KillOptions
+	// RespawnWindow is how long to watch for a killed process coming back
+	RespawnWindow time.Duration
+	// RefreshInterval rescans automatically when positive
+	RefreshInterval time.Duration
+	Theme           config.Theme
+	Keys            config.KeyBindings
+	// Project labels ports with the service names it declares
+	Project *project.Project
+	// Protocol limits the list to "tcp" or "udp" ports; "all" shows both
+	Protocol string
+	// Monitor, when set and running, updates the list with its own scan
+	// as soon as a port opens or closes
+	Monitor *monitor.Monitor
+	// ScanTimeout abandons scans that take longer; defaults to
+	// config.DefaultScanTimeout
+	ScanTimeout time.Duration
+}
+
 // Start launches the TUI
-func Start() {
-	p := tea.NewProgram(initialModel(), tea.WithAltScreen())
+func Start(opts Options) {
+	applyTheme(opts.Theme)
+	p := tea.NewProgram(initialModel(opts), tea.WithAltScreen())
 	if _, err := p.Run(); err != nil {
 		fmt.Fprintf(os.Stderr, "Error running TUI: %v\n", err)
 		os.Exit(1)
@@ -2,8 +2,12 @@ package tui
 
 import (
 	"fmt"
+	"time"
 
+	"github.com/NoaTamburrini/portman/internal/container"
 	"github.com/NoaTamburrini/portman/internal/process"
+	"github.com/NoaTamburrini/portman/internal/scanner"
+	"github.com/NoaTamburrini/portman/internal/systemd"
 
 	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
@@ -28,63 +32,211 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 
 		// Normal mode key handling
-		switch msg.String() {
-		case "ctrl+c", "q":
+		key := msg.String()
+		switch {
+		case key == "ctrl+c" || matches(key, m.keys.quit):
 			return m, tea.Quit
 
-		case "up", "k":
+		case matches(key, m.keys.cancel):
+			// The cancelled operations report back as they stop
+			if m.ops.cancelAll() {
+				m.statusMessage = "Cancelling..."
+				m.statusIsError = false
+			}
+
+		case matches(key, m.keys.up):
 			if m.cursor > 0 {
 				m.cursor--
 			}
 
-		case "down", "j":
+		case matches(key, m.keys.down):
 			if m.cursor < len(m.filteredPorts)-1 {
 				m.cursor++
 			}
 
-		case "r":
+		case matches(key, m.keys.refresh):
 			m.scanning = true
-			m.statusMessage = "Refreshing..."
+			m.statusMessage = fmt.Sprintf("Refreshing... (%s: cancel)", helpKey(m.keys.cancel))
 			m.statusIsError = false
-			return m, scanPorts
+			return m, m.scanPorts
 
-		case "/":
+		case matches(key, m.keys.filter):
 			m.filterMode = true
 			m.filterInput.Focus()
 			return m, textinput.Blink
 
-		case "enter":
+		case matches(key, m.keys.details):
+			m.showDetails = !m.showDetails
+
+		case matches(key, m.keys.connections):
+			m.showConnections = !m.showConnections
+
+		case matches(key, m.keys.sort):
+			if m.sortBy == sortByPort {
+				m.sortBy = sortByUptime
+				m.statusMessage = "Sorted by uptime, oldest first"
+			} else {
+				m.sortBy = sortByPort
+				m.statusMessage = "Sorted by port"
+			}
+			m.statusIsError = false
+			m.sortPorts()
+			m.filterPorts()
+
+		case matches(key, m.keys.usage):
+			// CPU usage is sampled between scans, so start with a fresh one
+			m.showUsage = !m.showUsage
+			if m.showUsage && !m.scanning {
+				m.scanning = true
+				return m, m.scanPorts
+			}
+
+		case matches(key, m.keys.relaunch):
+			if m.relaunch == nil {
+				m.statusMessage = "Nothing to relaunch: no process has been killed"
+				m.statusIsError = true
+				return m, nil
+			}
+			return m.startRelaunch()
+
+		case matches(key, m.keys.pause):
 			if len(m.filteredPorts) > 0 {
-				m.confirmingKill = true
-				selectedPort := m.filteredPorts[m.cursor]
-				m.statusMessage = fmt.Sprintf("Kill process on port %d (PID: %d)? [y/N]",
-					selectedPort.Number, selectedPort.PID)
-				m.statusIsError = false
+				return m.pause(m.filteredPorts[m.cursor])
+			}
+
+		case matches(key, m.keys.resume):
+			if len(m.filteredPorts) > 0 {
+				return m.resume(m.filteredPorts[m.cursor])
+			}
+
+		case matches(key, m.keys.killSupervisor):
+			if m.respawn != nil {
+				return m.startKillSupervisor(m.respawn)
 			}
+
+		case matches(key, m.keys.kill):
+			if len(m.filteredPorts) > 0 {
+				return m.startKill(m.filteredPorts[m.cursor])
+			}
+		}
+
+	case refreshTickMsg:
+		// Skip the automatic refresh while the user is mid-action
+		if m.scanning || m.filterMode || m.confirmingKill {
+			return m, m.refreshTick()
+		}
+		m.scanning = true
+		return m, tea.Batch(m.scanPorts, m.refreshTick())
+
+	case portEventMsg:
+		var cmds []tea.Cmd
+		if msg.retry {
+			m.eventPending = false
+		} else {
+			cmds = append(cmds, m.waitForEvent())
 		}
+		if m.eventPending {
+			// A retry is already on its way
+			return m, tea.Batch(cmds...)
+		}
+		// A scan already running would overwrite the monitor's newer
+		// ports, and the selection shouldn't move mid-action, so try
+		// again shortly
+		if m.scanning || m.filterMode || m.confirmingKill {
+			m.eventPending = true
+			cmds = append(cmds, tea.Tick(eventRetryDelay, func(time.Time) tea.Msg {
+				return portEventMsg{retry: true}
+			}))
+			return m, tea.Batch(cmds...)
+		}
+		m.scanning = true
+		return m, tea.Batch(append(cmds, m.monitorPorts)...)
 
 	case scanCompleteMsg:
 		m.scanning = false
-		if msg.err != nil {
+		if msg.cancelled {
+			m.statusMessage = "Scan cancelled"
+			m.statusIsError = false
+		} else if msg.err != nil {
 			m.statusMessage = fmt.Sprintf("Error: %v", msg.err)
 			m.statusIsError = true
 		} else {
 			m.ports = msg.ports
+			m.sortPorts()
 			m.filterPorts()
+			if m.lastKilled != nil {
+				if m.confirmingKill {
+					// Leave the open prompt alone and look again once it closes
+					return m, tea.Tick(respawnPollInterval, func(time.Time) tea.Msg {
+						return respawnCheckMsg{}
+					})
+				}
+				return m.checkRespawn()
+			}
 			m.statusMessage = fmt.Sprintf("Found %d active port(s)", len(m.ports))
 			m.statusIsError = false
 		}
 
+	case respawnCheckMsg:
+		if m.lastKilled == nil || m.scanning {
+			return m, nil
+		}
+		// Rescanning would move the selection mid-action, so try again shortly
+		if m.filterMode || m.confirmingKill {
+			return m, tea.Tick(respawnPollInterval, func(time.Time) tea.Msg {
+				return respawnCheckMsg{}
+			})
+		}
+		m.scanning = true
+		return m, m.scanPorts
+
 	case killCompleteMsg:
 		if msg.success {
 			m.statusMessage = fmt.Sprintf("✓ %s", msg.message)
 			m.statusIsError = false
+			m.respawn = nil
+			if msg.killed != nil && m.respawnWindow > 0 {
+				m.lastKilled = msg.killed
+				m.killedAt = time.Now()
+			}
+			if msg.launch != nil {
+				m.relaunch = msg.launch
+				m.relaunchPort = msg.killed.Number
+				m.statusMessage += fmt.Sprintf(" (%s: relaunch)", helpKey(m.keys.relaunch))
+			}
 			// Refresh after kill
-			return m, scanPorts
+			return m, m.scanPorts
 		} else {
 			m.statusMessage = fmt.Sprintf("✗ %s", msg.message)
 			m.statusIsError = true
 		}
+
+	case pauseCompleteMsg:
+		if !msg.success {
+			m.statusMessage = fmt.Sprintf("✗ %s", msg.message)
+			m.statusIsError = true
+			return m, nil
+		}
+		m.statusMessage = fmt.Sprintf("✓ %s", msg.message)
+		m.statusIsError = false
+		// Mark the process's ports without waiting for a rescan
+		for i := range m.ports {
+			if m.ports[i].PID == msg.pid {
+				m.ports[i].Paused = msg.paused
+			}
+		}
+		m.filterPorts()
+
+	case relaunchCompleteMsg:
+		if msg.err != nil {
+			m.statusMessage = fmt.Sprintf("✗ %v", msg.err)
+			m.statusIsError = true
+			return m, nil
+		}
+		m.statusMessage = fmt.Sprintf("✓ Relaunched %s (PID %d) for port %d", msg.launch.Argv[0], msg.pid, msg.port)
+		m.statusIsError = false
+		m.scanning = true
+		return m, m.scanPorts
 	}
 
 	return m, nil
@@ -112,24 +264,132 @@ func (m Model) handleFilterMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	return m, cmd
 }
 
+// startKill asks for confirmation before acting on the selected port,
+// offering to stop a container or systemd unit where signalling the
+// process would not do what the user wants
+func (m Model) startKill(selectedPort scanner.Port) (tea.Model, tea.Cmd) {
+	// Stopping its container or unit ends the process just as surely as a kill
+	if reason, protected := process.CheckProtected(selectedPort.PID, selectedPort.Number); protected && !m.killOptions.ForceProtected {
+		m.statusMessage = fmt.Sprintf("✗ Port %d is held by a protected process: %s (restart with --force-protected to override)",
+			selectedPort.Number, reason)
+		m.statusIsError = true
+		return m, nil
+	}
+
+	if c := selectedPort.Container; c != nil {
+		// Killing the proxy would break the runtime, so stop the container
+		m.confirmingKill = true
+		m.confirmPort = selectedPort
+		m.confirmAction = actionStopContainer
+		m.statusMessage = fmt.Sprintf("Port %d is published by %s container %s (%s). Stop the container? [y/N]",
+			selectedPort.Number, c.Runtime, c.Name, c.Image)
+		m.statusIsError = false
+		return m, nil
+	}
+
+	if u := selectedPort.Unit; u != nil && u.Restarts() {
+		m.confirmingKill = true
+		m.confirmPort = selectedPort
+		m.confirmAction = actionStopUnit
+		m.statusMessage = fmt.Sprintf("PID %d is managed by systemd unit %s and will be restarted if killed. Run `%s`? [y/k/N]",
+			selectedPort.PID, u.Name, u.StopCommand())
+		m.statusIsError = false
+		return m, nil
+	}
+
+	m.confirmingKill = true
+	m.confirmAction = actionKill
+	m.confirmPort = selectedPort
+	m.statusMessage = fmt.Sprintf("Kill process on port %d (PID: %d)? [y/N]",
+		selectedPort.Number, selectedPort.PID)
+	if n := len(selectedPort.Connections); n > 0 {
+		// Worth knowing before cutting clients off
+		m.statusMessage = fmt.Sprintf("Kill process on port %d (PID: %d)? %d client(s) still connected [y/N]",
+			selectedPort.Number, selectedPort.PID, n)
+	}
+	m.statusIsError = false
+	return m, nil
+}
+
+// checkRespawn looks for the last killed process coming back, rescanning
+// until the respawn window closes
+func (m Model) checkRespawn() (tea.Model, tea.Cmd) {
+	if r := scanner.DetectRespawn(*m.lastKilled, m.ports); r != nil {
+		m.lastKilled = nil
+		m.respawn = r
+		// Relaunching would start a second copy
+		m.relaunch = nil
+		m.statusMessage = fmt.Sprintf("⚠ Port %d is back: PID %d was restarted by %s (%s: kill supervisor)",
+			r.Port.Number, r.Port.PID, r.Restarter(), helpKey(m.keys.killSupervisor))
+		m.statusIsError = true
+		return m, nil
+	}
+
+	if time.Since(m.killedAt) >= m.respawnWindow {
+		m.lastKilled = nil
+		return m, nil
+	}
+	return m, tea.Tick(respawnPollInterval, func(time.Time) tea.Msg {
+		return respawnCheckMsg{}
+	})
+}
+
+// respawnPollInterval is how often the TUI rescans while watching for a respawn
+const respawnPollInterval = 500 * time.Millisecond
+
+// startKillSupervisor asks for confirmation before killing the process
+// manager that restarted a killed process
+func (m Model) startKillSupervisor(r *scanner.Respawn) (tea.Model, tea.Cmd) {
+	// systemd services are stopped through their unit
+	if u := r.Port.Unit; u != nil && u.Restarts() {
+		return m.startKill(r.Port)
+	}
+
+	if r.Supervisor == nil {
+		m.statusMessage = fmt.Sprintf("✗ Don't know what restarted PID %d", r.Port.PID)
+		m.statusIsError = true
+		return m, nil
+	}
+
+	m.confirmingKill = true
+	m.confirmAction = actionKillSupervisor
+	m.statusMessage = fmt.Sprintf("Kill supervisor %s (PID %d) and PID %d? [y/N]",
+		r.Supervisor.Name, r.Supervisor.PID, r.Port.PID)
+	m.statusIsError = false
+	return m, nil
+}
+
 func (m Model) handleConfirmMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	m.confirmingKill = false
 
+	if m.confirmAction == actionKillSupervisor {
+		if r := m.respawn; r != nil && (msg.String() == "y" || msg.String() == "Y") {
+			m.respawn = nil
+			return m.killSupervisor(r)
+		}
+		m.statusMessage = "Kill cancelled"
+		m.statusIsError = false
+		return m, nil
+	}
+
+	selectedPort := m.confirmPort
+
 	switch msg.String() {
 	case "y", "Y":
-		if len(m.filteredPorts) > 0 {
-			selectedPort := m.filteredPorts[m.cursor]
-			m.statusMessage = fmt.Sprintf("Killing process on port %d...", selectedPort.Number)
-			m.statusIsError = false
+		switch m.confirmAction {
+		case actionStopContainer:
+			return m.stopContainer(selectedPort)
+		case actionStopUnit:
+			return m.stopUnit(selectedPort)
+		default:
+			return m.kill(selectedPort)
+		}
 
-			// Kill the process
-			return m, func() tea.Msg {
-				result := process.KillProcess(selectedPort.PID)
-				return killCompleteMsg{
-					success: result.Success,
-					message: result.Message,
-				}
-			}
+	case "k", "K":
+		// Kill a supervised process anyway
+		if m.confirmAction == actionStopUnit {
+			selectedPort.Unit = nil
+			return m.startKill(selectedPort)
 		}
 	}
 
@@ -137,3 +397,160 @@ func (m Model) handleConfirmMode(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 	m.statusIsError = false
 	return m, nil
 }
+
+// kill kills the process holding a port
+func (m Model) kill(selectedPort scanner.Port) (tea.Model, tea.Cmd) {
+	m.statusMessage = fmt.Sprintf("Killing process on port %d... (%s: stop waiting)", selectedPort.Number, helpKey(m.keys.cancel))
+	m.statusIsError = false
+
+	opts := m.killOptions
+	opts.Port = selectedPort.Number
+	return m, func() tea.Msg {
+		ctx, done := m.ops.begin()
+		defer done()
+
+		// Remember how the process was started so R can bring it back
+		var launch *process.Launch
+		if l, err := process.CaptureLaunch(selectedPort.PID); err == nil {
+			launch = &l
+		}
+
+		result := process.KillProcessContext(ctx, selectedPort.PID, opts)
+		if !result.Success {
+			launch = nil
+		}
+		return killCompleteMsg{
+			success: result.Success,
+			message: result.Message,
+			killed:  &selectedPort,
+			launch:  launch,
+		}
+	}
+}
+
+// startRelaunch starts the last killed process again, detached, with the
+// command line, directory and environment it had
+func (m Model) startRelaunch() (tea.Model, tea.Cmd) {
+	launch, port := *m.relaunch, m.relaunchPort
+	m.relaunch = nil
+	// A relaunch isn't a respawn to warn about
+	m.lastKilled = nil
+	m.statusMessage = fmt.Sprintf("Relaunching %s in %s...", launch, launch.Dir)
+	m.statusIsError = false
+
+	return m, func() tea.Msg {
+		pid, err := launch.Start()
+		return relaunchCompleteMsg{pid: pid, port: port, launch: launch, err: err}
+	}
+}
+
+// pause suspends the process holding a port until it is resumed
+func (m Model) pause(selectedPort scanner.Port) (tea.Model, tea.Cmd) {
+	if c := selectedPort.Container; c != nil {
+		// Freezing the proxy would hang the runtime rather than the container
+		m.statusMessage = fmt.Sprintf("✗ Port %d is published by %s container %s; use `%s pause %s` instead",
+			selectedPort.Number, c.Runtime, c.Name, c.Runtime, c.Name)
+		m.statusIsError = true
+		return m, nil
+	}
+	if selectedPort.Paused {
+		m.statusMessage = fmt.Sprintf("PID %d is already paused (%s: resume)", selectedPort.PID, helpKey(m.keys.resume))
+		m.statusIsError = false
+		return m, nil
+	}
+
+	opts := m.killOptions
+	opts.Port = selectedPort.Number
+	return m, func() tea.Msg {
+		result := process.PauseProcess(selectedPort.PID, opts)
+		message := result.Message
+		if result.Success {
+			message = fmt.Sprintf("PID %d (%s) on port %d paused (%s: resume)",
+				selectedPort.PID, selectedPort.ProcessName, selectedPort.Number, helpKey(m.keys.resume))
+		}
+		return pauseCompleteMsg{success: result.Success, message: message, pid: selectedPort.PID, paused: true}
+	}
+}
+
+// resume continues the paused process holding a port
+func (m Model) resume(selectedPort scanner.Port) (tea.Model, tea.Cmd) {
+	return m, func() tea.Msg {
+		result := process.ResumeProcess(selectedPort.PID)
+		message := result.Message
+		if result.Success {
+			message = fmt.Sprintf("PID %d (%s) on port %d resumed", selectedPort.PID, selectedPort.ProcessName, selectedPort.Number)
+		}
+		return pauseCompleteMsg{success: result.Success, message: message, pid: selectedPort.PID, paused: false}
+	}
+}
+
+// killSupervisor kills a process manager, then the process it restarted
+// if it didn't take it down with it
+func (m Model) killSupervisor(r *scanner.Respawn) (tea.Model, tea.Cmd) {
+	m.statusMessage = fmt.Sprintf("Killing supervisor %s... (%s: stop waiting)", r.Supervisor.Name, helpKey(m.keys.cancel))
+	m.statusIsError = false
+
+	opts := m.killOptions
+	return m, func() tea.Msg {
+		ctx, done := m.ops.begin()
+		defer done()
+
+		result := process.KillProcessContext(ctx, r.Supervisor.PID, opts)
+		if !result.Success {
+			return killCompleteMsg{success: false, message: result.Message}
+		}
+
+		if process.IsProcessRunning(r.Port.PID) {
+			childOpts := opts
+			childOpts.Port = r.Port.Number
+			if child := process.KillProcessContext(ctx, r.Port.PID, childOpts); !child.Success {
+				return killCompleteMsg{success: false, message: child.Message}
+			}
+		}
+		return killCompleteMsg{
+			success: true,
+			message: fmt.Sprintf("Supervisor %s and PID %d killed", r.Supervisor.Name, r.Port.PID),
+		}
+	}
+}
+
+// stopContainer stops the container publishing a port
+func (m Model) stopContainer(selectedPort scanner.Port) (tea.Model, tea.Cmd) {
+	c := *selectedPort.Container
+	m.statusMessage = fmt.Sprintf("Stopping container %s... (%s: stop waiting)", c.Name, helpKey(m.keys.cancel))
+	m.statusIsError = false
+
+	timeout := m.killOptions.Timeout
+	return m, func() tea.Msg {
+		ctx, done := m.ops.begin()
+		defer done()
+
+		record := selectedPort.StopRecord("container " + c.Name)
+		err := container.Stop(ctx, c, timeout)
+		process.RecordStop(record, err)
+		if err != nil {
+			return killCompleteMsg{success: false, message: err.Error()}
+		}
+		return killCompleteMsg{success: true, message: fmt.Sprintf("Container %s stopped", c.Name)}
+	}
+}
+
+// stopUnit stops the systemd unit supervising the process on a port
+func (m Model) stopUnit(selectedPort scanner.Port) (tea.Model, tea.Cmd) {
+	u := *selectedPort.Unit
+	m.statusMessage = fmt.Sprintf("Stopping %s... (%s: stop waiting)", u.Name, helpKey(m.keys.cancel))
+	m.statusIsError = false
+
+	return m, func() tea.Msg {
+		ctx, done := m.ops.begin()
+		defer done()
+
+		record := selectedPort.StopRecord("unit " + u.Name)
+		err := systemd.Stop(ctx, u)
+		process.RecordStop(record, err)
+		if err != nil {
+			return killCompleteMsg{success: false, message: err.Error()}
+		}
+		return killCompleteMsg{success: true, message: fmt.Sprintf("Unit %s stopped", u.Name)}
+	}
+}
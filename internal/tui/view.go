@@ -4,6 +4,9 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/NoaTamburrini/portman/internal/process"
+	"github.com/NoaTamburrini/portman/internal/scanner"
+
 	"github.com/charmbracelet/lipgloss"
 )
 
@@ -40,13 +43,23 @@ func (m Model) View() string {
 		b.WriteString("\n\n")
 	} else {
 		// Header
-		header := fmt.Sprintf("%-8s %-10s %-8s %-20s %-30s",
-			"PORT", "PROTOCOL", "PID", "PROCESS", "COMMAND")
+		header := fmt.Sprintf("%-8s %-14s %-10s %-8s %-20s %-8s %-6s %-20s ",
+			"PORT", "SERVICE", "PROTOCOL", "PID", "PROCESS", "UPTIME", "CONNS", "PROJECT")
+		if m.showUsage {
+			header += fmt.Sprintf("%-8s %-6s %-4s %-5s ", "RSS", "CPU%", "THR", "FDS")
+		}
+		header += fmt.Sprintf("%-30s", "COMMAND")
 		b.WriteString(headerStyle.Render(header))
 		b.WriteString("\n")
 
 		// Calculate how many rows we can show
 		maxRows := m.height - 12 // Reserve space for title, status, help
+		if m.showDetails {
+			maxRows -= detailsHeight
+		}
+		if m.showConnections {
+			maxRows -= connectionsHeight
+		}
 		if maxRows < 5 {
 			maxRows = 5
 		}
@@ -71,17 +84,30 @@ func (m Model) View() string {
 
 			// Truncate command if too long
 			command := p.Command
+			if p.Container != nil {
+				command = fmt.Sprintf("%s: %s (%s)", p.Container.Runtime, p.Container.Name, p.Container.Image)
+			}
+			if p.Namespace != "" {
+				command = fmt.Sprintf("[%s] %s", p.Namespace, command)
+			}
 			if len(command) > 30 {
 				command = command[:27] + "..."
 			}
 
-			row := fmt.Sprintf("%-8d %-10s %-8d %-20s %-30s",
+			row := fmt.Sprintf("%-8d %-14s %-10s %-8d %-20s %-8s %-6s %-20s ",
 				p.Number,
+				truncate(m.serviceName(p.Number), 14),
 				p.Protocol,
 				p.PID,
-				truncate(p.ProcessName, 20),
-				command,
+				renderProcessName(p),
+				renderUptime(p),
+				renderConnectionCount(p),
+				truncate(projectName(p), 20),
 			)
+			if m.showUsage {
+				row += renderUsage(p.Usage)
+			}
+			row += fmt.Sprintf("%-30s", command)
 
 			// Apply style based on selection
 			if i == m.cursor {
@@ -100,6 +126,18 @@ func (m Model) View() string {
 			b.WriteString(renderMuted(indicator))
 			b.WriteString("\n")
 		}
+
+		if m.showDetails {
+			b.WriteString("\n")
+			b.WriteString(m.renderDetails(m.filteredPorts[m.cursor]))
+			b.WriteString("\n")
+		}
+
+		if m.showConnections {
+			b.WriteString("\n")
+			b.WriteString(m.renderConnections(m.filteredPorts[m.cursor]))
+			b.WriteString("\n")
+		}
 	}
 
 	b.WriteString("\n")
@@ -109,15 +147,193 @@ func (m Model) View() string {
 	if m.filterMode {
 		help = "Enter: apply filter • Esc: cancel"
 	} else if m.confirmingKill {
-		help = "y: confirm kill • n: cancel"
+		switch m.confirmAction {
+		case actionStopContainer:
+			help = "y: stop container • n: cancel"
+		case actionStopUnit:
+			help = "y: stop unit • k: kill process anyway • n: cancel"
+		case actionKillSupervisor:
+			help = "y: kill supervisor • n: cancel"
+		default:
+			help = "y: confirm kill • n: cancel"
+		}
 	} else {
-		help = "↑/↓ j/k: navigate • Enter: kill • r: refresh • /: filter • q: quit"
+		help = fmt.Sprintf("%s %s: navigate • %s: kill • %s: pause • %s: details • %s: connections • %s: usage • %s: sort • %s: refresh • %s: filter • %s: quit",
+			helpKey(m.keys.up), helpKey(m.keys.down), helpKey(m.keys.kill), helpKey(m.keys.pause), helpKey(m.keys.details),
+			helpKey(m.keys.connections), helpKey(m.keys.usage), helpKey(m.keys.sort), helpKey(m.keys.refresh), helpKey(m.keys.filter),
+			helpKey(m.keys.quit))
+		if m.relaunch != nil {
+			help += fmt.Sprintf(" • %s: relaunch", helpKey(m.keys.relaunch))
+		}
+		if len(m.filteredPorts) > 0 && m.filteredPorts[m.cursor].Paused {
+			help += fmt.Sprintf(" • %s: resume", helpKey(m.keys.resume))
+		}
 	}
 	b.WriteString(helpStyle.Render(help))
 
 	return b.String()
 }
 
+// detailsHeight is the number of lines the details panel takes up
+const detailsHeight = 10
+
+// renderDetails shows everything known about the selected port
+func (m Model) renderDetails(p scanner.Port) string {
+	var lines []string
+	field := func(name, value string) {
+		if value != "" {
+			lines = append(lines, fmt.Sprintf("%-10s %s", name+":", value))
+		}
+	}
+
+	field("Port", fmt.Sprintf("%d/%s", p.Number, p.Protocol))
+	if p.Paused {
+		field("State", p.State+" (process paused)")
+	} else {
+		field("State", p.State)
+	}
+	field("Service", m.serviceName(p.Number))
+	field("Process", fmt.Sprintf("%s (PID %d)", p.ProcessName, p.PID))
+	field("Command", p.Command)
+	if p.Container != nil {
+		field("Container", fmt.Sprintf("%s (%s, %s %s)", p.Container.Name, p.Container.Image, p.Container.Runtime, p.Container.ID))
+	}
+	if !p.StartTime.IsZero() {
+		field("Started", fmt.Sprintf("%s (%s ago)", p.StartTime.Format("2006-01-02 15:04:05"), process.FormatUptime(p.Uptime())))
+	}
+	field("Cwd", p.Cwd)
+	if p.Project != nil {
+		field("Project", fmt.Sprintf("%s at %s", p.Project, p.Project.Root))
+	}
+	field("Namespace", p.Namespace)
+	if u := p.Usage; u != nil {
+		usage := fmt.Sprintf("%s RSS, %d threads", process.FormatBytes(u.RSS), u.Threads)
+		if u.CPUSampled {
+			usage = fmt.Sprintf("%.1f%% CPU, %s", u.CPU, usage)
+		}
+		if u.FDs > 0 {
+			usage += fmt.Sprintf(", %d open fds", u.FDs)
+		}
+		field("Usage", usage)
+	}
+	if p.Unit != nil {
+		unit := p.Unit.Name
+		if p.Unit.User {
+			unit += " (user)"
+		}
+		if p.Unit.Restarts() {
+			unit += " - supervised, a plain kill will be restarted"
+		}
+		field("Unit", unit)
+	}
+
+	width := m.width - 4
+	if width < 40 {
+		width = 40
+	}
+	return detailsStyle.Width(width).Render(strings.Join(lines, "\n"))
+}
+
+// connectionsHeight is the number of lines the connections panel takes up
+const connectionsHeight = 13
+
+// maxConnectionRows is how many connections the panel lists
+const maxConnectionRows = 8
+
+// renderConnections lists the clients connected to the selected port
+func (m Model) renderConnections(p scanner.Port) string {
+	var lines []string
+	switch {
+	case p.Protocol != "tcp":
+		lines = append(lines, fmt.Sprintf("Port %d/%s is connectionless; there are no connections to list", p.Number, p.Protocol))
+	case len(p.Connections) == 0:
+		lines = append(lines, fmt.Sprintf("No clients connected to port %d", p.Number))
+	default:
+		lines = append(lines, fmt.Sprintf("%d connection(s) to port %d", len(p.Connections), p.Number))
+		header := fmt.Sprintf("%-40s %-12s %-24s", "REMOTE", "STATE", "CLIENT")
+		if scanner.QueuesKnown() {
+			header += fmt.Sprintf(" %8s %8s", "SEND-Q", "RECV-Q")
+		}
+		lines = append(lines, header)
+
+		for i, c := range p.Connections {
+			if i == maxConnectionRows {
+				lines = append(lines, renderMuted(fmt.Sprintf("... and %d more", len(p.Connections)-i)))
+				break
+			}
+			client := "-"
+			if c.ClientPID != 0 {
+				client = fmt.Sprintf("%s (PID %d)", c.ClientName, c.ClientPID)
+			}
+			line := fmt.Sprintf("%-40s %-12s %-24s", truncate(c.Remote, 40), c.State, truncate(client, 24))
+			if scanner.QueuesKnown() {
+				line += fmt.Sprintf(" %8d %8d", c.SendQueue, c.RecvQueue)
+			}
+			lines = append(lines, line)
+		}
+	}
+
+	width := m.width - 4
+	if width < 40 {
+		width = 40
+	}
+	return detailsStyle.Width(width).Render(strings.Join(lines, "\n"))
+}
+
+// renderConnectionCount formats the CONNS column: the number of clients
+// connected to a TCP listener
+func renderConnectionCount(p scanner.Port) string {
+	if p.Protocol != "tcp" {
+		return "-"
+	}
+	return fmt.Sprintf("%d", len(p.Connections))
+}
+
+// projectName names the checkout a port's owner runs from, or "-"
+func projectName(p scanner.Port) string {
+	if p.Project == nil {
+		return "-"
+	}
+	return p.Project.String()
+}
+
+// pausedMarker is appended to the process name of a paused process
+const pausedMarker = " [paused]"
+
+// renderProcessName formats the PROCESS column, marking paused processes
+func renderProcessName(p scanner.Port) string {
+	if !p.Paused {
+		return truncate(p.ProcessName, 20)
+	}
+	return truncate(p.ProcessName, 20-len(pausedMarker)) + pausedMarker
+}
+
+// renderUptime formats how long a port's owner has been running
+func renderUptime(p scanner.Port) string {
+	if p.StartTime.IsZero() {
+		return "-"
+	}
+	return process.FormatUptime(p.Uptime())
+}
+
+// renderUsage formats the resource usage columns of a row
+func renderUsage(u *process.Usage) string {
+	rss, cpu, threads, fds := "-", "-", "-", "-"
+	if u != nil {
+		rss = process.FormatBytes(u.RSS)
+		if u.CPUSampled {
+			cpu = fmt.Sprintf("%.1f", u.CPU)
+		}
+		if u.Threads > 0 {
+			threads = fmt.Sprintf("%d", u.Threads)
+		}
+		if u.FDs > 0 {
+			fds = fmt.Sprintf("%d", u.FDs)
+		}
+	}
+	return fmt.Sprintf("%-8s %-6s %-4s %-5s ", rss, cpu, threads, fds)
+}
+
 func truncate(s string, maxLen int) string {
 	if len(s) <= maxLen {
 		return s
@@ -126,5 +342,5 @@ func truncate(s string, maxLen int) string {
 }
 
 func renderMuted(s string) string {
-	return lipgloss.NewStyle().Foreground(lipgloss.Color("241")).Render(s)
+	return lipgloss.NewStyle().Foreground(mutedColor).Render(s)
 }
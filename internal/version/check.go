@@ -23,10 +23,15 @@ type GitHubRelease struct {
 	TagName string `json:"tag_name"`
 }
 
-// CheckForUpdate checks if a newer version is available and prints a message
-func CheckForUpdate() {
+// CheckForUpdate checks if a newer version is available and prints a message.
+// It checks at most once per period; a zero period disables the check.
+func CheckForUpdate(period time.Duration) {
+	if period <= 0 {
+		return
+	}
+
 	// Check if we should skip (last check was recent)
-	if shouldSkipCheck() {
+	if shouldSkipCheck(period) {
 		return
 	}
 
@@ -69,14 +74,14 @@ func getLatestVersion() (string, error) {
 	return release.TagName, nil
 }
 
-func shouldSkipCheck() bool {
+func shouldSkipCheck(period time.Duration) bool {
 	cacheFile := getCacheFile()
 	info, err := os.Stat(cacheFile)
 	if err != nil {
 		return false // No cache file, should check
 	}
 
-	return time.Since(info.ModTime()) < CheckPeriod
+	return time.Since(info.ModTime()) < period
 }
 
 func updateLastCheck() {